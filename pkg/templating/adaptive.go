@@ -0,0 +1,80 @@
+package templating
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+const (
+	adaptiveInitialLimit = 10
+	adaptiveMinLimit     = 1
+	adaptiveMaxLimit     = 500
+	// adaptiveTolerance is the factor by which the latency may exceed the baseline before
+	// the backends are considered congested.
+	adaptiveTolerance = 2
+	// adaptiveDrift is the share by which the baseline follows higher latencies, so it
+	// adapts to backends that became permanently slower.
+	adaptiveDrift = 0.01
+)
+
+// adaptiveLimiter bounds the number of concurrent fragment requests across all renders.
+// The limit grows additively while latencies stay close to the baseline and shrinks
+// multiplicatively once they climb.
+type adaptiveLimiter struct {
+	mu       sync.Mutex
+	limit    float64
+	inflight int
+	baseline time.Duration
+	released chan struct{}
+}
+
+func newAdaptiveLimiter() *adaptiveLimiter {
+	return &adaptiveLimiter{limit: adaptiveInitialLimit, released: make(chan struct{})}
+}
+
+// acquire blocks until a request is permitted by the current limit or the context is done.
+func (a *adaptiveLimiter) acquire(ctx context.Context) error {
+	for {
+		a.mu.Lock()
+		if a.inflight < int(a.limit) {
+			a.inflight++
+			a.mu.Unlock()
+			return nil
+		}
+		released := a.released
+		a.mu.Unlock()
+
+		select {
+		case <-released:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// release frees the slot of a finished request and adjusts the limit by its latency.
+func (a *adaptiveLimiter) release(latency time.Duration) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.inflight--
+	switch {
+	case a.baseline == 0 || latency < a.baseline:
+		a.baseline = latency
+	default:
+		a.baseline += time.Duration(float64(latency-a.baseline) * adaptiveDrift)
+	}
+
+	if latency > a.baseline*adaptiveTolerance {
+		a.limit /= 2
+		if a.limit < adaptiveMinLimit {
+			a.limit = adaptiveMinLimit
+		}
+	} else if a.limit < adaptiveMaxLimit {
+		a.limit++
+	}
+
+	close(a.released)
+	a.released = make(chan struct{})
+}
@@ -0,0 +1,66 @@
+package templating
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAdaptiveLimiter_Latency(t *testing.T) {
+	limiter := newAdaptiveLimiter()
+	observe := func(latency time.Duration, times int) {
+		for i := 0; i < times; i++ {
+			assert.NoError(t, limiter.acquire(context.Background()))
+			limiter.release(latency)
+		}
+	}
+
+	observe(10*time.Millisecond, 10)
+	fast := limiter.limit
+	assert.Greater(t, fast, float64(adaptiveInitialLimit))
+
+	observe(100*time.Millisecond, 3)
+	slow := limiter.limit
+	assert.Less(t, slow, fast)
+
+	observe(10*time.Millisecond, 10)
+	assert.Greater(t, limiter.limit, slow)
+}
+
+func TestAdaptiveLimiter_Acquire(t *testing.T) {
+	limiter := newAdaptiveLimiter()
+	limiter.limit = 1
+
+	assert.NoError(t, limiter.acquire(context.Background()))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, limiter.acquire(ctx), context.DeadlineExceeded)
+
+	acquired := make(chan error)
+	go func() {
+		acquired <- limiter.acquire(context.Background())
+	}()
+	limiter.release(time.Millisecond)
+	assert.NoError(t, <-acquired)
+}
+
+func TestTemplater_Parse_AdaptiveConcurrency(t *testing.T) {
+	dummy := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("Content-Type", "text/html")
+		writer.Write([]byte("<content>Foo</content>"))
+	}))
+	defer dummy.Close()
+
+	templater := New(WithAdaptiveConcurrency())
+	actual, err := templater.Parse(strings.NewReader(fmt.Sprintf(`<html><body><fragment src="%[1]s"></fragment><fragment src="%[1]s"></fragment></body></html>`, dummy.URL)))
+	assert.NoError(t, err)
+	assert.Equal(t, "<html><head></head><body><><content>Foo</content></><><content>Foo</content></></body></html>", actual)
+	assert.Equal(t, 0, templater.adaptive.inflight)
+}
@@ -0,0 +1,157 @@
+package templating
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+type MixedContentPolicy int
+
+const (
+	// MixedContentAllow leaves insecure asset urls of fragments untouched.
+	MixedContentAllow MixedContentPolicy = iota
+	// MixedContentUpgrade rewrites insecure http asset urls of fragments to https.
+	MixedContentUpgrade
+	// MixedContentBlock renders the fallback of fragments referencing insecure http assets.
+	MixedContentBlock
+)
+
+type ProtocolRelativePolicy int
+
+const (
+	// ProtocolRelativeKeep leaves protocol-relative asset urls of fragments untouched.
+	ProtocolRelativeKeep ProtocolRelativePolicy = iota
+	// ProtocolRelativeHTTPS rewrites protocol-relative asset urls of fragments to https.
+	ProtocolRelativeHTTPS
+	// ProtocolRelativeSource rewrites protocol-relative asset urls of fragments to the scheme of the fragment src.
+	ProtocolRelativeSource
+)
+
+var (
+	ErrorMixedContent = errors.New("fragment references insecure assets")
+)
+
+// assetAttributes maps the elements referencing an asset to the attribute holding its url.
+var assetAttributes = map[atom.Atom]string{
+	atom.Img:    "src",
+	atom.Script: "src",
+	atom.Source: "src",
+	atom.Video:  "src",
+	atom.Audio:  "src",
+	atom.Track:  "src",
+	atom.Embed:  "src",
+	atom.Link:   "href",
+}
+
+// assetRelations lists the link relations pointing at an asset rather than a document.
+var assetRelations = []string{"stylesheet", "icon", "preload", "modulepreload", "prefetch"}
+
+func (t *Templater) rewriteAssets(content *html.Node) {
+	if t.assetCDN == nil {
+		return
+	}
+
+	visit(content, func(node *html.Node) {
+		key, ok := assetAttribute(node)
+		if !ok {
+			return
+		}
+
+		for i, value := range node.Attr {
+			if value.Key == key && value.Val != "" {
+				node.Attr[i].Val = t.assetCDN(value.Val)
+			}
+		}
+	})
+}
+
+// qualifyAssets applies the protocol-relative policy to the asset urls of the content. Absolute
+// urls are left to the mixed content policy.
+func (t *Templater) qualifyAssets(source string, content *html.Node) {
+	scheme := "https"
+	switch t.protocolRelative {
+	case ProtocolRelativeKeep:
+		return
+	case ProtocolRelativeSource:
+		if location, err := url.Parse(source); err == nil && location.Scheme != "" {
+			scheme = location.Scheme
+		}
+	}
+
+	visit(content, func(node *html.Node) {
+		key, ok := assetAttribute(node)
+		if !ok {
+			return
+		}
+
+		for i, value := range node.Attr {
+			if value.Key == key && strings.HasPrefix(value.Val, "//") {
+				node.Attr[i].Val = scheme + ":" + value.Val
+			}
+		}
+	})
+}
+
+// secureAssets applies the mixed content policy to the asset urls of the content.
+func (t *Templater) secureAssets(content *html.Node) error {
+	if t.mixedContent == MixedContentAllow {
+		return nil
+	}
+
+	var err error
+	visit(content, func(node *html.Node) {
+		key, ok := assetAttribute(node)
+		if !ok {
+			return
+		}
+
+		for i, value := range node.Attr {
+			if value.Key != key || !strings.HasPrefix(strings.ToLower(value.Val), "http://") {
+				continue
+			}
+
+			if t.mixedContent == MixedContentBlock {
+				err = ErrorMixedContent
+				return
+			}
+			node.Attr[i].Val = "https://" + value.Val[len("http://"):]
+		}
+	})
+	return err
+}
+
+func assetAttribute(node *html.Node) (string, bool) {
+	if node.Type != html.ElementNode {
+		return "", false
+	}
+
+	key, ok := assetAttributes[node.DataAtom]
+	if !ok {
+		return "", false
+	}
+
+	if node.DataAtom == atom.Link {
+		for _, relation := range strings.Fields(strings.ToLower(attribute(*node, "rel"))) {
+			for _, candidate := range assetRelations {
+				if relation == candidate {
+					return key, true
+				}
+			}
+		}
+		return "", false
+	}
+
+	return key, true
+}
+
+// visit calls fn for node and every node below it in document order.
+func visit(node *html.Node, fn func(node *html.Node)) {
+	fn(node)
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		visit(child, fn)
+	}
+}
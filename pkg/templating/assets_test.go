@@ -0,0 +1,110 @@
+package templating
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/html"
+)
+
+func TestTemplater_Resolve_AssetCDN(t *testing.T) {
+	dummy := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("Content-Type", "text/html")
+		writer.Write([]byte(`<content><img src="https://origin/a.png"><a href="https://origin/page">Page</a><link rel="stylesheet" href="https://origin/a.css"><link rel="canonical" href="https://origin/page"></content>`))
+	}))
+
+	templater := New(WithAssetCDN(func(assetURL string) string {
+		return strings.Replace(assetURL, "https://origin/", "https://cdn.example.com/", 1)
+	}))
+	resolved, err := templater.Resolve(html.Node{
+		Data: fragmentIdentifier,
+		Attr: []html.Attribute{{Key: "src", Val: dummy.URL}},
+	})
+	assert.NoError(t, err)
+
+	var actual bytes.Buffer
+	html.Render(&actual, resolved)
+	assert.Equal(t, fmt.Sprint(
+		`<><content>`,
+		`<img src="https://cdn.example.com/a.png"/>`,
+		`<a href="https://origin/page">Page</a>`,
+		`<link rel="stylesheet" href="https://cdn.example.com/a.css"/>`,
+		`<link rel="canonical" href="https://origin/page"/>`,
+		`</content></>`,
+	), actual.String())
+}
+
+func TestTemplater_Parse_MixedContentPolicy(t *testing.T) {
+	dummy := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("Content-Type", "text/html")
+		writer.Write([]byte(`<content><img src="http://origin/a.png"><script src="https://origin/a.js"></script><a href="http://origin/page">Page</a></content>`))
+	}))
+	defer dummy.Close()
+
+	tt := []struct {
+		policy   MixedContentPolicy
+		expected string
+	}{
+		{
+			policy:   MixedContentAllow,
+			expected: `<html><head><script src="https://origin/a.js"></script></head><body><><content><img src="http://origin/a.png"/><a href="http://origin/page">Page</a></content></></body></html>`,
+		},
+		{
+			policy:   MixedContentUpgrade,
+			expected: `<html><head><script src="https://origin/a.js"></script></head><body><><content><img src="https://origin/a.png"/><a href="http://origin/page">Page</a></content></></body></html>`,
+		},
+		{
+			policy:   MixedContentBlock,
+			expected: `<html><head></head><body><>Fallback</></body></html>`,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run("", func(t *testing.T) {
+			templater := New(WithMixedContentPolicy(tc.policy))
+			actual, err := templater.Parse(strings.NewReader(fmt.Sprintf(`<html><body><fragment src="%s">Fallback</fragment></body></html>`, dummy.URL)))
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+}
+
+func TestTemplater_Parse_ProtocolRelativePolicy(t *testing.T) {
+	dummy := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("Content-Type", "text/html")
+		writer.Write([]byte(`<content><img src="//cdn.example.com/a.png"><img src="http://origin/b.png"><img src="https://origin/c.png"><a href="//origin/page">Page</a></content>`))
+	}))
+	defer dummy.Close()
+
+	tt := []struct {
+		policy   ProtocolRelativePolicy
+		expected string
+	}{
+		{
+			policy:   ProtocolRelativeKeep,
+			expected: `<html><head></head><body><><content><img src="//cdn.example.com/a.png"/><img src="http://origin/b.png"/><img src="https://origin/c.png"/><a href="//origin/page">Page</a></content></></body></html>`,
+		},
+		{
+			policy:   ProtocolRelativeHTTPS,
+			expected: `<html><head></head><body><><content><img src="https://cdn.example.com/a.png"/><img src="http://origin/b.png"/><img src="https://origin/c.png"/><a href="//origin/page">Page</a></content></></body></html>`,
+		},
+		{
+			policy:   ProtocolRelativeSource,
+			expected: `<html><head></head><body><><content><img src="http://cdn.example.com/a.png"/><img src="http://origin/b.png"/><img src="https://origin/c.png"/><a href="//origin/page">Page</a></content></></body></html>`,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run("", func(t *testing.T) {
+			templater := New(WithProtocolRelativePolicy(tc.policy))
+			actual, err := templater.Parse(strings.NewReader(fmt.Sprintf(`<html><body><fragment src="%s">Fallback</fragment></body></html>`, dummy.URL)))
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package templating
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// Backoff determines the delay before a retry, given the number of the retry starting at 1
+// and the error of the previous attempt.
+type Backoff interface {
+	Next(attempt int, err error) time.Duration
+}
+
+// BackoffFunc adapts an ordinary function to the Backoff interface.
+type BackoffFunc func(attempt int, err error) time.Duration
+
+func (f BackoffFunc) Next(attempt int, err error) time.Duration {
+	return f(attempt, err)
+}
+
+// ConstantBackoff waits the same delay before every retry.
+func ConstantBackoff(delay time.Duration) Backoff {
+	return BackoffFunc(func(attempt int, err error) time.Duration {
+		return delay
+	})
+}
+
+// ExponentialBackoff doubles the delay with every retry, starting at base and capped at max.
+func ExponentialBackoff(base, max time.Duration) Backoff {
+	return BackoffFunc(func(attempt int, err error) time.Duration {
+		return capped(float64(base)*math.Pow(2, float64(attempt-1)), max)
+	})
+}
+
+// DecorrelatedJitterBackoff waits a random delay between base and three times the previous delay,
+// capped at max, which spreads the retries of concurrent renders. The previous delay starts over at
+// base with the first retry and is shared by all renders of the templater.
+func DecorrelatedJitterBackoff(base, max time.Duration) Backoff {
+	return &decorrelatedJitter{base: base, max: max}
+}
+
+type decorrelatedJitter struct {
+	base, max time.Duration
+
+	mu       sync.Mutex
+	previous time.Duration
+}
+
+func (b *decorrelatedJitter) Next(attempt int, err error) time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if attempt <= 1 || b.previous < b.base {
+		b.previous = b.base
+	}
+
+	delay := b.base
+	if upper := 3 * b.previous; upper > b.base {
+		delay += time.Duration(rand.Int63n(int64(upper - b.base)))
+	}
+	b.previous = capped(float64(delay), b.max)
+	return b.previous
+}
+
+func capped(delay float64, max time.Duration) time.Duration {
+	if max > 0 && delay > float64(max) {
+		return max
+	}
+	return time.Duration(delay)
+}
+
+// backOff waits the delay of the backoff before the retry, returning false if the context is done first.
+func (t *Templater) backOff(ctx context.Context, attempt int, err error) bool {
+	if t.backoff == nil {
+		return ctx.Err() == nil
+	}
+
+	delay := t.backoff.Next(attempt, err)
+	if delay <= 0 {
+		return ctx.Err() == nil
+	}
+
+	select {
+	case <-t.after(delay):
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
@@ -0,0 +1,113 @@
+package templating
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTemplater_Parse_Backoff(t *testing.T) {
+	var requests int32
+	dummy := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("Content-Type", "text/html")
+		if atomic.AddInt32(&requests, 1) <= 2 {
+			return
+		}
+		writer.Write([]byte(`<content>hello</content>`))
+	}))
+	defer dummy.Close()
+
+	var (
+		mu       sync.Mutex
+		attempts []int
+		errs     []error
+	)
+	backoff := BackoffFunc(func(attempt int, err error) time.Duration {
+		mu.Lock()
+		defer mu.Unlock()
+		attempts = append(attempts, attempt)
+		errs = append(errs, err)
+		return 50 * time.Millisecond
+	})
+
+	templater := New(WithRetryOnEmpty(2), WithBackoff(backoff))
+	start := time.Now()
+	actual, err := templater.Parse(strings.NewReader(fmt.Sprintf(`<html><body><fragment src="%s"></fragment></body></html>`, dummy.URL)))
+	assert.NoError(t, err)
+	assert.Equal(t, `<html><head></head><body><><content>hello</content></></body></html>`, actual)
+	assert.GreaterOrEqual(t, int64(time.Since(start)), int64(100*time.Millisecond))
+	assert.Equal(t, []int{1, 2}, attempts)
+	assert.Equal(t, []error{ErrorEmptyResponse, ErrorEmptyResponse}, errs)
+}
+
+func TestBackoff_Next(t *testing.T) {
+	testCases := map[string]struct {
+		backoff  Backoff
+		attempt  int
+		expected time.Duration
+	}{
+		"constant": {
+			backoff:  ConstantBackoff(time.Second),
+			attempt:  3,
+			expected: time.Second,
+		},
+		"exponential first retry": {
+			backoff:  ExponentialBackoff(100*time.Millisecond, time.Second),
+			attempt:  1,
+			expected: 100 * time.Millisecond,
+		},
+		"exponential doubles": {
+			backoff:  ExponentialBackoff(100*time.Millisecond, time.Second),
+			attempt:  3,
+			expected: 400 * time.Millisecond,
+		},
+		"exponential is capped": {
+			backoff:  ExponentialBackoff(100*time.Millisecond, time.Second),
+			attempt:  10,
+			expected: time.Second,
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, tc.backoff.Next(tc.attempt, errors.New("failed")))
+		})
+	}
+}
+
+func TestDecorrelatedJitterBackoff(t *testing.T) {
+	backoff := DecorrelatedJitterBackoff(100*time.Millisecond, time.Second)
+	for attempt := 1; attempt <= 10; attempt++ {
+		delay := backoff.Next(attempt, nil)
+		assert.GreaterOrEqual(t, int64(delay), int64(100*time.Millisecond))
+		assert.LessOrEqual(t, int64(delay), int64(time.Second))
+	}
+
+	t.Run("should grow from the previous delay", func(t *testing.T) {
+		backoff := DecorrelatedJitterBackoff(100*time.Millisecond, time.Hour)
+		previous := 100 * time.Millisecond
+		for attempt := 1; attempt <= 10; attempt++ {
+			delay := backoff.Next(attempt, nil)
+			assert.GreaterOrEqual(t, int64(delay), int64(100*time.Millisecond))
+			assert.Less(t, int64(delay), int64(3*previous))
+			previous = delay
+		}
+	})
+
+	t.Run("should start over with the first retry", func(t *testing.T) {
+		backoff := DecorrelatedJitterBackoff(100*time.Millisecond, time.Hour)
+		for attempt := 1; attempt <= 10; attempt++ {
+			backoff.Next(attempt, nil)
+		}
+		assert.Less(t, int64(backoff.Next(1, nil)), int64(300*time.Millisecond))
+	})
+}
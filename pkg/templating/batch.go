@@ -0,0 +1,40 @@
+package templating
+
+import (
+	"golang.org/x/net/html"
+)
+
+// batch holds the fragments of a node that are composed together, at the nesting depth of the node.
+type batch struct {
+	root       *html.Node
+	fragments  []*html.Node
+	dependents []*html.Node
+	depth      int
+}
+
+// collect gathers the fragments of the node, separating those depending on another fragment.
+// Lazy fragments are deferred to the client and fragments of already composed regions are skipped when enabled.
+func (t *Templater) collect(node *html.Node, depth int) batch {
+	adoptMarkedTemplates(node, t.tagName())
+	paginate(node, t.tagName())
+
+	result := batch{root: node, depth: depth}
+	for _, element := range t.Walk(node) {
+		switch element.Data {
+		case t.tagName():
+			if lazy(*element) {
+				deferred(element)
+				continue
+			}
+			if t.composedMarker && isComposed(element) {
+				continue
+			}
+			if attribute(*element, dependsOnAttribute) != "" {
+				result.dependents = append(result.dependents, element)
+				continue
+			}
+			result.fragments = append(result.fragments, element)
+		}
+	}
+	return result
+}
@@ -0,0 +1,33 @@
+package templating
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+)
+
+// byteOrderMark is the utf-8 encoded byte order mark some backends prefix their responses with.
+var byteOrderMark = []byte{0xEF, 0xBB, 0xBF}
+
+// stripLeading removes a leading byte order mark from the response and, if configured,
+// the whitespace before the markup.
+func (t *Templater) stripLeading(reader io.Reader) io.Reader {
+	buffered := bufio.NewReader(reader)
+	if prefix, err := buffered.Peek(len(byteOrderMark)); err == nil && bytes.Equal(prefix, byteOrderMark) {
+		buffered.Discard(len(byteOrderMark))
+	}
+
+	if t.trimLeadingWhitespace {
+		for {
+			value, err := buffered.ReadByte()
+			if err != nil {
+				break
+			}
+			if value != ' ' && value != '\t' && value != '\n' && value != '\r' && value != '\f' {
+				buffered.UnreadByte()
+				break
+			}
+		}
+	}
+	return buffered
+}
@@ -0,0 +1,54 @@
+package templating
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTemplater_Parse_StripLeading(t *testing.T) {
+	tt := map[string]struct {
+		options  []Option
+		body     string
+		expected string
+	}{
+		"byte order mark": {
+			body:     "\uFEFF<content>Foo</content>",
+			expected: "<html><head></head><body><><content>Foo</content></></body></html>",
+		},
+		"byte order mark and whitespace": {
+			body:     "\uFEFF\n  <content>Foo</content>",
+			expected: "<html><head></head><body><>\n  <content>Foo</content></></body></html>",
+		},
+		"trimmed whitespace": {
+			options:  []Option{WithTrimLeadingWhitespace()},
+			body:     "\uFEFF\n  <content>Foo</content>",
+			expected: "<html><head></head><body><><content>Foo</content></></body></html>",
+		},
+		"inner whitespace is kept": {
+			options:  []Option{WithTrimLeadingWhitespace()},
+			body:     "\n<content>Foo</content> <content>Bar</content>",
+			expected: "<html><head></head><body><><content>Foo</content> <content>Bar</content></></body></html>",
+		},
+	}
+
+	for name, tc := range tt {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			dummy := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+				writer.Header().Set("Content-Type", "text/html")
+				writer.Write([]byte(tc.body))
+			}))
+			defer dummy.Close()
+
+			templater := New(tc.options...)
+			actual, err := templater.Parse(strings.NewReader(fmt.Sprintf(`<html><body><fragment src="%s"></fragment></body></html>`, dummy.URL)))
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+}
@@ -0,0 +1,148 @@
+package templating
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/url"
+	"sync"
+	"time"
+)
+
+var (
+	ErrorCircuitOpen           = errors.New("circuit of the fragment host is open")
+	ErrorConnectionCircuitOpen = errors.New("connection circuit of the fragment host is open")
+)
+
+// circuitBreaker stops requesting the fragments of a host for a cooldown, once the host failed
+// a number of consecutive times within a window. After the cooldown a single failure opens it again.
+// Failures are counted by the counts function, backend failures by default.
+type circuitBreaker struct {
+	mu        sync.Mutex
+	threshold int
+	window    time.Duration
+	cooldown  time.Duration
+	circuits  map[string]*circuit
+	counts    func(error) bool
+	open      error
+}
+
+type circuit struct {
+	failures int
+	since    time.Time
+	until    time.Time
+}
+
+// allow returns ErrorCircuitOpen while the circuit of the host of the source is open.
+func (b *circuitBreaker) allow(source string, now time.Time) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if current, ok := b.circuits[hostOf(source)]; ok && now.Before(current.until) {
+		if b.open != nil {
+			return b.open
+		}
+		return ErrorCircuitOpen
+	}
+	return nil
+}
+
+// record closes the circuit of the host of the source on success and counts failures of the backend.
+func (b *circuitBreaker) record(source string, err error, now time.Time) {
+	if err != nil && !b.counting(err) {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	host := hostOf(source)
+	if err == nil {
+		delete(b.circuits, host)
+		return
+	}
+
+	if b.circuits == nil {
+		b.circuits = make(map[string]*circuit)
+	}
+	current, ok := b.circuits[host]
+	if !ok {
+		current = &circuit{}
+		b.circuits[host] = current
+	}
+
+	switch {
+	case !current.until.IsZero():
+		// the trial request after the cooldown failed
+		current.until = now.Add(b.cooldown)
+		return
+	case current.failures == 0 || now.Sub(current.since) > b.window:
+		current.failures, current.since = 0, now
+	}
+
+	current.failures++
+	if current.failures >= b.threshold {
+		current.until = now.Add(b.cooldown)
+	}
+}
+
+// counting reports whether the breaker counts the error as a failure.
+func (b *circuitBreaker) counting(err error) bool {
+	if b.counts != nil {
+		return b.counts(err)
+	}
+	return failing(err)
+}
+
+// allow returns the error of the first breaker whose circuit of the host of the source is open.
+func (t *Templater) allow(source string, now time.Time) error {
+	if t.connectionBreaker != nil {
+		if err := t.connectionBreaker.allow(source, now); err != nil {
+			return err
+		}
+	}
+	if t.breaker != nil {
+		return t.breaker.allow(source, now)
+	}
+	return nil
+}
+
+// recordBreakers records the outcome of the request in the breakers. With a connection breaker,
+// connection failures no longer count for the breaker of backend failures. Requests cancelled by the
+// caller count for neither.
+func (t *Templater) recordBreakers(source string, err error, now time.Time) {
+	if t.connectionBreaker != nil {
+		t.connectionBreaker.record(source, err, now)
+		if connectionFailure(err) {
+			return
+		}
+	}
+	if t.breaker != nil {
+		t.breaker.record(source, err, now)
+	}
+}
+
+// connectionFailure reports whether the request failed to connect to the host or lost the
+// connection, e.g. on dns errors, refused or reset connections.
+func connectionFailure(err error) bool {
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+
+	var opError *net.OpError
+	var dnsError *net.DNSError
+	return errors.As(err, &opError) || errors.As(err, &dnsError)
+}
+
+// failing reports whether the error is a failure of the backend rather than of the composition.
+func failing(err error) bool {
+	return transient(err) || errors.Is(err, context.DeadlineExceeded)
+}
+
+func hostOf(source string) string {
+	location, err := url.Parse(source)
+	if err != nil {
+		return source
+	}
+	return location.Host
+}
@@ -0,0 +1,149 @@
+package templating
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTemplater_Parse_CircuitBreaker(t *testing.T) {
+	var (
+		requests int32
+		healthy  int32
+	)
+	dummy := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("Content-Type", "text/html")
+		atomic.AddInt32(&requests, 1)
+		if atomic.LoadInt32(&healthy) == 0 {
+			writer.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		writer.Write([]byte("<content>Foo</content>"))
+	}))
+	defer dummy.Close()
+
+	const (
+		fallback = "<html><head></head><body><>Bar</></body></html>"
+		content  = "<html><head></head><body><><content>Foo</content></></body></html>"
+	)
+
+	clock := newFakeClock()
+	templater := New(WithClock(clock), WithCircuitBreaker(3, time.Minute, 30*time.Second))
+	render := func(path string) string {
+		actual, err := templater.Parse(strings.NewReader(fmt.Sprintf(`<html><body><fragment src="%s%s">Bar</fragment></body></html>`, dummy.URL, path)))
+		assert.NoError(t, err)
+		return actual
+	}
+
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, fallback, render("/teaser"))
+	}
+	assert.Equal(t, int32(3), atomic.LoadInt32(&requests))
+
+	start := time.Now()
+	assert.Equal(t, fallback, render("/other"))
+	assert.Less(t, int64(time.Since(start)), int64(50*time.Millisecond))
+	assert.Equal(t, int32(3), atomic.LoadInt32(&requests))
+
+	clock.Advance(30 * time.Second)
+	assert.Equal(t, fallback, render("/teaser"))
+	assert.Equal(t, int32(4), atomic.LoadInt32(&requests))
+	assert.Equal(t, fallback, render("/teaser"))
+	assert.Equal(t, int32(4), atomic.LoadInt32(&requests))
+
+	atomic.StoreInt32(&healthy, 1)
+	clock.Advance(30 * time.Second)
+	assert.Equal(t, content, render("/teaser"))
+	assert.Equal(t, content, render("/teaser"))
+	assert.Equal(t, int32(6), atomic.LoadInt32(&requests))
+}
+
+func TestCircuitBreaker_Window(t *testing.T) {
+	breaker := &circuitBreaker{threshold: 2, window: time.Minute, cooldown: time.Minute}
+	now := time.Now()
+
+	breaker.record("http://example.com/a", StatusError{StatusCode: http.StatusBadGateway}, now)
+	breaker.record("http://example.com/b", StatusError{StatusCode: http.StatusBadGateway}, now.Add(2*time.Minute))
+	assert.NoError(t, breaker.allow("http://example.com/a", now.Add(2*time.Minute)))
+
+	breaker.record("http://example.com/a", StatusError{StatusCode: http.StatusNotFound}, now.Add(2*time.Minute))
+	assert.NoError(t, breaker.allow("http://example.com/a", now.Add(2*time.Minute)))
+
+	breaker.record("http://example.com/a", StatusError{StatusCode: http.StatusBadGateway}, now.Add(3*time.Minute))
+	assert.ErrorIs(t, breaker.allow("http://example.com/a", now.Add(3*time.Minute)), ErrorCircuitOpen)
+	assert.NoError(t, breaker.allow("http://other.example.com/a", now.Add(3*time.Minute)))
+}
+
+func TestTemplater_Parse_ConnectionBreaker(t *testing.T) {
+	var requests int32
+	broken := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("Content-Type", "text/html")
+		atomic.AddInt32(&requests, 1)
+		writer.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer broken.Close()
+
+	refused := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {}))
+	refused.Close()
+
+	clock := newFakeClock()
+	templater := New(WithClock(clock), WithConnectionBreaker(2, time.Minute, 30*time.Second), WithCircuitBreaker(3, time.Minute, 30*time.Second))
+	render := func(source string) []FragmentError {
+		actual, failures, err := templater.ParseWithResult(strings.NewReader(fmt.Sprintf(`<html><body><fragment src="%s">Bar</fragment></body></html>`, source)))
+		assert.NoError(t, err)
+		assert.Equal(t, "<html><head></head><body><>Bar</></body></html>", actual)
+		assert.Len(t, failures, 1)
+		return failures
+	}
+
+	for i := 0; i < 2; i++ {
+		render(refused.URL)
+	}
+	assert.ErrorIs(t, render(refused.URL)[0], ErrorConnectionCircuitOpen)
+
+	for i := 0; i < 3; i++ {
+		render(broken.URL)
+	}
+	assert.ErrorIs(t, render(broken.URL)[0], ErrorCircuitOpen)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&requests))
+
+	stats := templater.Stats()
+	assert.Equal(t, map[string]CircuitState{refused.Listener.Addr().String(): CircuitOpen}, stats.ConnectionCircuits)
+	assert.Equal(t, map[string]CircuitState{broken.Listener.Addr().String(): CircuitOpen}, stats.Circuits)
+}
+
+func TestTemplater_ParseContext_CircuitBreaker(t *testing.T) {
+	var requests int32
+	dummy := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("Content-Type", "text/html")
+		atomic.AddInt32(&requests, 1)
+		time.Sleep(100 * time.Millisecond)
+		writer.Write([]byte("<content>Foo</content>"))
+	}))
+	defer dummy.Close()
+
+	templater := New(WithCircuitBreaker(1, time.Minute, time.Minute), WithConnectionBreaker(1, time.Minute, time.Minute), WithRetries(2))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+	}()
+
+	actual, err := templater.ParseContext(ctx, strings.NewReader(fmt.Sprintf(`<html><body><fragment src="%s">Bar</fragment></body></html>`, dummy.URL)))
+	assert.NoError(t, err)
+	assert.Equal(t, "<html><head></head><body><>Bar</></body></html>", actual)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&requests))
+
+	stats := templater.Stats()
+	assert.Empty(t, stats.Circuits)
+	assert.Empty(t, stats.ConnectionCircuits)
+}
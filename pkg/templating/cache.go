@@ -0,0 +1,149 @@
+package templating
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/html"
+)
+
+const (
+	noCacheAttribute = "no-cache"
+
+	// defaultCacheEntries is the number of responses cached before the cache makes room.
+	defaultCacheEntries = 10000
+)
+
+// responseCache keeps resolved fragments for the max-age of their response, or the default ttl
+// if the response does not state one, so shared fragments are not requested on every render.
+// Once it holds the maximum number of entries, expired entries are swept and, if still full,
+// the entry expiring first is evicted.
+type responseCache struct {
+	mu         sync.Mutex
+	ttl        time.Duration
+	maxEntries int
+	entries    map[string]cacheEntry
+	hits       int64
+	misses     int64
+}
+
+type cacheEntry struct {
+	response *fragmentResponse
+	expires  time.Time
+}
+
+// lookup returns a copy of the cached response of the key, if it has not expired yet.
+func (r *responseCache) lookup(key string, now time.Time) (*fragmentResponse, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	entry, ok := r.entries[key]
+	if !ok {
+		r.misses++
+		return nil, false
+	}
+	if !now.Before(entry.expires) {
+		delete(r.entries, key)
+		r.misses++
+		return nil, false
+	}
+	r.hits++
+	return entry.response.clone(), true
+}
+
+// store caches a copy of the response for the key, unless its Cache-Control forbids it.
+func (r *responseCache) store(key string, response *fragmentResponse, now time.Time) {
+	ttl, ok := freshness(response.header, r.ttl)
+	if !ok {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.entries == nil {
+		r.entries = make(map[string]cacheEntry)
+	}
+	if _, ok := r.entries[key]; !ok && len(r.entries) >= r.capacity() {
+		r.makeRoom(now)
+	}
+	r.entries[key] = cacheEntry{response: response.clone(), expires: now.Add(ttl)}
+}
+
+// capacity returns the maximum number of entries, defaultCacheEntries unless configured otherwise.
+func (r *responseCache) capacity() int {
+	if r.maxEntries <= 0 {
+		return defaultCacheEntries
+	}
+	return r.maxEntries
+}
+
+// makeRoom deletes all expired entries, or the entry expiring first if none expired.
+func (r *responseCache) makeRoom(now time.Time) {
+	var (
+		first   string
+		expires time.Time
+	)
+	for key, entry := range r.entries {
+		if !now.Before(entry.expires) {
+			delete(r.entries, key)
+			continue
+		}
+		if first == "" || entry.expires.Before(expires) {
+			first, expires = key, entry.expires
+		}
+	}
+	if len(r.entries) >= r.capacity() {
+		delete(r.entries, first)
+	}
+}
+
+// freshness returns how long a response may be cached by its Cache-Control header, where
+// s-maxage takes precedence over max-age and responses without either use the default ttl.
+func freshness(header http.Header, ttl time.Duration) (time.Duration, bool) {
+	var maxAge, sharedMaxAge = -1, -1
+	for _, directive := range strings.Split(header.Get("Cache-Control"), ",") {
+		name, value, _ := strings.Cut(strings.TrimSpace(directive), "=")
+		switch strings.ToLower(name) {
+		case "no-store", "no-cache", "private":
+			return 0, false
+		case "max-age":
+			if seconds, err := strconv.Atoi(strings.Trim(value, `"`)); err == nil {
+				maxAge = seconds
+			}
+		case "s-maxage":
+			if seconds, err := strconv.Atoi(strings.Trim(value, `"`)); err == nil {
+				sharedMaxAge = seconds
+			}
+		}
+	}
+
+	switch {
+	case sharedMaxAge >= 0:
+		ttl = time.Duration(sharedMaxAge) * time.Second
+	case maxAge >= 0:
+		ttl = time.Duration(maxAge) * time.Second
+	}
+	return ttl, ttl > 0
+}
+
+// cacheable reports whether the response of the fragment may be served from the cache, which holds
+// for GET requests only.
+func (t *Templater) cacheable(node html.Node) bool {
+	if t.cache == nil {
+		return false
+	}
+	if _, ok := lookupAttribute(node, noCacheAttribute); ok {
+		return false
+	}
+	return attribute(node, asAttribute) != asWebSocket && methodFor(node) == http.MethodGet
+}
+
+// responseKey identifies the response of the fragment by its normalized url and everything
+// else varying the response.
+func (t *Templater) responseKey(c *composition, node html.Node, source string) string {
+	return strings.Join([]string{t.cacheKey(source), attribute(node, asAttribute), tableContext(node), c.languageFor(node), c.forwardKey()}, "\n")
+}
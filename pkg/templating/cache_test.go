@@ -0,0 +1,102 @@
+package templating
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/html"
+)
+
+func TestTemplater_Parse_Cache(t *testing.T) {
+	testCases := map[string]struct {
+		cacheControl string
+		attributes   string
+		advance      time.Duration
+		requests     int32
+	}{
+		"cached for the default ttl": {
+			advance:  30 * time.Second,
+			requests: 1,
+		},
+		"expired after the default ttl": {
+			advance:  time.Minute,
+			requests: 2,
+		},
+		"cached for the max-age": {
+			cacheControl: "public, max-age=300",
+			advance:      4 * time.Minute,
+			requests:     1,
+		},
+		"expired after the max-age": {
+			cacheControl: "max-age=10",
+			advance:      10 * time.Second,
+			requests:     2,
+		},
+		"s-maxage takes precedence": {
+			cacheControl: "max-age=10, s-maxage=300",
+			advance:      time.Minute,
+			requests:     1,
+		},
+		"no-store": {
+			cacheControl: "no-store",
+			requests:     2,
+		},
+		"no-cache attribute": {
+			attributes: "no-cache",
+			requests:   2,
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			var requests int32
+			dummy := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+				writer.Header().Set("Content-Type", "text/html")
+				atomic.AddInt32(&requests, 1)
+				if tc.cacheControl != "" {
+					writer.Header().Set("Cache-Control", tc.cacheControl)
+				}
+				writer.Write([]byte(`<content><a href="/page">Page</a></content>`))
+			}))
+			defer dummy.Close()
+
+			clock := newFakeClock()
+			templater := New(WithClock(clock), WithCache(time.Minute))
+			document := fmt.Sprintf(`<html><body><fragment src="%s/nav?b=2&a=1" %s></fragment></body></html>`, dummy.URL, tc.attributes)
+
+			for i := 0; i < 2; i++ {
+				actual, err := templater.Parse(strings.NewReader(document))
+				assert.NoError(t, err)
+				assert.Equal(t, `<html><head></head><body><><content><a href="/page">Page</a></content></></body></html>`, actual)
+				clock.Advance(tc.advance)
+			}
+			assert.Equal(t, tc.requests, atomic.LoadInt32(&requests))
+		})
+	}
+}
+
+func TestResponseCache_Capacity(t *testing.T) {
+	now := time.Now()
+	response := &fragmentResponse{content: &html.Node{Type: html.ElementNode}, header: http.Header{}}
+	cache := &responseCache{ttl: time.Minute, maxEntries: 2}
+
+	cache.store("expired", response, now.Add(-time.Minute))
+	cache.store("first", response, now)
+	cache.store("second", response, now.Add(time.Second))
+	assert.Len(t, cache.entries, 2)
+	assert.NotContains(t, cache.entries, "expired")
+
+	cache.store("third", response, now.Add(2*time.Second))
+	assert.Len(t, cache.entries, 2)
+	assert.NotContains(t, cache.entries, "first")
+
+	_, ok := cache.lookup("second", now)
+	assert.True(t, ok)
+}
@@ -0,0 +1,36 @@
+package templating
+
+import (
+	"net/url"
+)
+
+// cacheKey normalizes the fragment url for use in cache keys, sorting its query parameters
+// unless disabled by WithOrderedQuery, so equivalent urls share their cache entries.
+func (t *Templater) cacheKey(source string) string {
+	if t.orderedQuery {
+		return source
+	}
+
+	location, err := url.Parse(source)
+	if err != nil {
+		return source
+	}
+	return normalizeQuery(location)
+}
+
+// normalizeQuery renders the url with its query parameters sorted by key. The order of repeated
+// parameters is kept, as backends usually depend on it.
+func normalizeQuery(location *url.URL) string {
+	if location.RawQuery == "" {
+		return location.String()
+	}
+
+	query, err := url.ParseQuery(location.RawQuery)
+	if err != nil {
+		return location.String()
+	}
+
+	normalized := *location
+	normalized.RawQuery = query.Encode()
+	return normalized.String()
+}
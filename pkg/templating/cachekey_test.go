@@ -0,0 +1,83 @@
+package templating
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTemplater_Parse_QueryOrder(t *testing.T) {
+	testCases := map[string]struct {
+		options  []Option
+		requests int32
+	}{
+		"normalized": {
+			requests: 1,
+		},
+		"ordered": {
+			options:  []Option{WithOrderedQuery()},
+			requests: 2,
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			var requests int32
+			dummy := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+				writer.Header().Set("Content-Type", "text/html")
+				atomic.AddInt32(&requests, 1)
+				writer.WriteHeader(http.StatusInternalServerError)
+			}))
+			defer dummy.Close()
+
+			templater := New(append(tc.options, WithNegativeCacheTTL(time.Minute))...)
+			for _, query := range []string{"a=1&b=2", "b=2&a=1"} {
+				actual, err := templater.Parse(strings.NewReader(fmt.Sprintf(`<html><body><fragment src="%s/teaser?%s">Bar</fragment></body></html>`, dummy.URL, query)))
+				assert.NoError(t, err)
+				assert.Equal(t, "<html><head></head><body><>Bar</></body></html>", actual)
+			}
+			assert.Equal(t, tc.requests, atomic.LoadInt32(&requests))
+		})
+	}
+}
+
+func TestTemplater_CacheKey(t *testing.T) {
+	testCases := map[string]struct {
+		options  []Option
+		source   string
+		expected string
+	}{
+		"sorted by key": {
+			source:   "https://example.com/nav?b=2&a=1",
+			expected: "https://example.com/nav?a=1&b=2",
+		},
+		"repeated parameters keep their order": {
+			source:   "https://example.com/nav?b=2&a=3&a=1",
+			expected: "https://example.com/nav?a=3&a=1&b=2",
+		},
+		"without query": {
+			source:   "https://example.com/nav",
+			expected: "https://example.com/nav",
+		},
+		"ordered": {
+			options:  []Option{WithOrderedQuery()},
+			source:   "https://example.com/nav?b=2&a=1",
+			expected: "https://example.com/nav?b=2&a=1",
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			templater := New(tc.options...)
+			assert.Equal(t, tc.expected, templater.cacheKey(tc.source))
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package templating
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"mime"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/charset"
+)
+
+// sniffLength is the number of leading bytes of a response searched for a declared encoding.
+const sniffLength = 1024
+
+// decoding converts the response to utf-8 before parsing it, if it declares another encoding by the
+// charset of the content type, a byte order mark or a meta element. Responses without a declared
+// encoding are parsed as utf-8 rather than guessed.
+func decoding(parse func(io.Reader) (*html.Node, error), contentType string) func(io.Reader) (*html.Node, error) {
+	return func(reader io.Reader) (*html.Node, error) {
+		buffered := bufio.NewReaderSize(reader, sniffLength)
+		preview, err := buffered.Peek(sniffLength)
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+
+		name := declaredEncoding(preview, contentType)
+		if name == "" || name == "utf-8" {
+			return parse(buffered)
+		}
+
+		decoded, err := charset.NewReaderLabel(name, buffered)
+		if err != nil {
+			return nil, err
+		}
+		return parse(decoded)
+	}
+}
+
+// declaredEncoding returns the name of the encoding declared by the content type, a byte order mark
+// or a meta element within the preview, empty if none is declared.
+func declaredEncoding(preview []byte, contentType string) string {
+	if _, name, certain := charset.DetermineEncoding(preview, contentType); certain {
+		return name
+	}
+
+	if label := metaCharset(preview); label != "" {
+		if encoding, name := charset.Lookup(label); encoding != nil {
+			return name
+		}
+	}
+	return ""
+}
+
+// metaCharset returns the charset declared by the first meta element of the preview, either by its
+// charset attribute or the content of a http-equiv="content-type" meta element.
+func metaCharset(preview []byte) string {
+	tokenizer := html.NewTokenizer(bytes.NewReader(preview))
+	for {
+		switch tokenizer.Next() {
+		case html.ErrorToken:
+			return ""
+		case html.StartTagToken, html.SelfClosingTagToken:
+			token := tokenizer.Token()
+			if token.Data != "meta" {
+				continue
+			}
+
+			var equiv, content string
+			for _, attr := range token.Attr {
+				switch strings.ToLower(attr.Key) {
+				case "charset":
+					return attr.Val
+				case "http-equiv":
+					equiv = strings.ToLower(attr.Val)
+				case "content":
+					content = attr.Val
+				}
+			}
+			if equiv == "content-type" {
+				if _, params, err := mime.ParseMediaType(content); err == nil && params["charset"] != "" {
+					return params["charset"]
+				}
+			}
+		}
+	}
+}
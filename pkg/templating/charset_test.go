@@ -0,0 +1,63 @@
+package templating
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTemplater_Parse_Charset(t *testing.T) {
+	dummy := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		switch request.URL.Path {
+		case "/header":
+			writer.Header().Set("Content-Type", "text/html; charset=ISO-8859-1")
+			writer.Write([]byte("<content>Caf\xe9 cr\xe8me br\xfbl\xe9e</content>"))
+		case "/meta":
+			writer.Header().Set("Content-Type", "text/html")
+			writer.Write([]byte(`<meta charset="iso-8859-1"><content>Caf` + "\xe9" + `</content>`))
+		case "/undeclared":
+			writer.Header().Set("Content-Type", "text/html")
+			writer.Write([]byte("<content>" + strings.Repeat("a", 1100) + " Grüße</content>"))
+		default:
+			writer.Header().Set("Content-Type", "text/html; charset=utf-8")
+			writer.Write([]byte("<content>Café</content>"))
+		}
+	}))
+	defer dummy.Close()
+
+	testCases := map[string]struct {
+		path     string
+		expected string
+	}{
+		"charset of the content type": {
+			path:     "/header",
+			expected: `<html><head></head><body><><content>Café crème brûlée</content></></body></html>`,
+		},
+		"charset of a meta element": {
+			path:     "/meta",
+			expected: `<html><head></head><body><><meta charset="iso-8859-1"/><content>Café</content></></body></html>`,
+		},
+		"utf-8 without a declared encoding": {
+			path:     "/undeclared",
+			expected: `<html><head></head><body><><content>` + strings.Repeat("a", 1100) + ` Grüße</content></></body></html>`,
+		},
+		"utf-8": {
+			path:     "/utf8",
+			expected: `<html><head></head><body><><content>Café</content></></body></html>`,
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			templater := New()
+			actual, err := templater.Parse(strings.NewReader(fmt.Sprintf(`<html><body><fragment src="%s%s"></fragment></body></html>`, dummy.URL, tc.path)))
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+}
@@ -0,0 +1,32 @@
+package templating
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type roundTripperFunc func(request *http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(request *http.Request) (*http.Response, error) {
+	return f(request)
+}
+
+func TestTemplater_Parse_WithClient(t *testing.T) {
+	client := &http.Client{Transport: roundTripperFunc(func(request *http.Request) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{"Content-Type": {"text/html"}},
+			Body:       io.NopCloser(strings.NewReader("<content>" + request.URL.Host + "</content>")),
+			Request:    request,
+		}, nil
+	})}
+
+	templater := New(WithClient(client))
+	actual, err := templater.Parse(strings.NewReader(`<html><body><fragment src="http://mock.example.com/teaser">Bar</fragment></body></html>`))
+	assert.NoError(t, err)
+	assert.Equal(t, "<html><head></head><body><><content>mock.example.com</content></></body></html>", actual)
+}
@@ -0,0 +1,37 @@
+package templating
+
+import "time"
+
+// Clock is the source of time of a templater, replaceable to test time dependent behaviour deterministically.
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+func (t *Templater) now() time.Time {
+	if t.clock == nil {
+		return time.Now()
+	}
+	return t.clock.Now()
+}
+
+func (t *Templater) since(start time.Time) time.Duration {
+	return t.now().Sub(start)
+}
+
+func (t *Templater) after(d time.Duration) <-chan time.Time {
+	if t.clock == nil {
+		return time.After(d)
+	}
+	return t.clock.After(d)
+}
@@ -0,0 +1,188 @@
+package templating
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeTimer struct {
+	at time.Time
+	c  chan time.Time
+}
+
+// fakeClock only moves on when advanced and fires the pending timers that are due.
+type fakeClock struct {
+	mu     sync.Mutex
+	now    time.Time
+	timers []fakeTimer
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Now()}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	timer := fakeTimer{at: c.now.Add(d), c: make(chan time.Time, 1)}
+	if d <= 0 {
+		timer.c <- c.now
+		return timer.c
+	}
+	c.timers = append(c.timers, timer)
+	return timer.c
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.now = c.now.Add(d)
+	pending := c.timers[:0]
+	for _, timer := range c.timers {
+		if timer.at.After(c.now) {
+			pending = append(pending, timer)
+			continue
+		}
+		timer.c <- c.now
+	}
+	c.timers = pending
+}
+
+func (c *fakeClock) Timers() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.timers)
+}
+
+func TestTemplater_Clock_FragmentSLO(t *testing.T) {
+	clock := newFakeClock()
+	dummy := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("Content-Type", "text/html")
+		if request.URL.Path == "/slow" {
+			clock.Advance(time.Minute)
+		}
+		writer.Write([]byte("<content>Foo</content>"))
+	}))
+	defer dummy.Close()
+
+	var events []Event
+	templater := New(WithClock(clock), WithFragmentSLO(time.Second), WithObserver(ObserverFunc(func(event Event) {
+		events = append(events, event)
+	})))
+
+	_, err := templater.Parse(strings.NewReader(fmt.Sprintf(`<html><body><fragment src="%[1]s/slow"></fragment><fragment src="%[1]s/fast"></fragment></body></html>`, dummy.URL)))
+	assert.NoError(t, err)
+
+	if assert.Len(t, events, 1) {
+		assert.Equal(t, dummy.URL+"/slow", events[0].Source)
+		assert.Equal(t, time.Minute, events[0].Duration)
+	}
+}
+
+func TestTemplater_Clock_TimeoutGroup(t *testing.T) {
+	clock := newFakeClock()
+	var requests []string
+	dummy := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("Content-Type", "text/html")
+		requests = append(requests, request.URL.Path)
+		clock.Advance(time.Minute)
+		writer.Write([]byte("<content>Foo</content>"))
+	}))
+	defer dummy.Close()
+
+	templater := New(WithClock(clock), WithTimeoutGroup("sidebar", time.Minute))
+	actual, err := templater.Parse(strings.NewReader(fmt.Sprintf(`<html><body><fragment group="sidebar" src="%[1]s/a">A</fragment><fragment group="sidebar" src="%[1]s/b">B</fragment></body></html>`, dummy.URL)))
+	assert.NoError(t, err)
+
+	assert.Len(t, requests, 1, "the budget is spent after the first member")
+	assert.Equal(t, 1, strings.Count(actual, "<><content>Foo</content></>"))
+}
+
+func TestTemplater_Clock_RateLimit(t *testing.T) {
+	dummy := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("Content-Type", "text/html")
+		writer.Write([]byte("<content>Foo</content>"))
+	}))
+	defer dummy.Close()
+
+	clock := newFakeClock()
+	templater := New(WithClock(clock), WithRateLimit(1, 1))
+
+	done := make(chan string)
+	go func() {
+		actual, _ := templater.Parse(strings.NewReader(fmt.Sprintf(`<html><body><fragment src="%[1]s"></fragment><fragment src="%[1]s"></fragment></body></html>`, dummy.URL)))
+		done <- actual
+	}()
+
+	assert.Eventually(t, func() bool {
+		return clock.Timers() == 1
+	}, time.Second, time.Millisecond, "the second fragment should wait for the limiter")
+
+	select {
+	case <-done:
+		assert.Fail(t, "the render should wait until the clock advanced")
+	default:
+	}
+
+	clock.Advance(time.Second)
+	assert.Equal(t, "<html><head></head><body><><content>Foo</content></><><content>Foo</content></></body></html>", <-done)
+}
+
+func TestTemplater_Clock_Expiry(t *testing.T) {
+	tt := map[string]struct {
+		status  int
+		options []Option
+	}{
+		"cache":          {status: http.StatusOK, options: []Option{WithCache(time.Minute)}},
+		"negative cache": {status: http.StatusInternalServerError, options: []Option{WithNegativeCacheTTL(time.Minute)}},
+		"breaker":        {status: http.StatusInternalServerError, options: []Option{WithCircuitBreaker(1, time.Minute, time.Minute)}},
+	}
+
+	for name, tc := range tt {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			var requests int32
+			dummy := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+				writer.Header().Set("Content-Type", "text/html")
+				atomic.AddInt32(&requests, 1)
+				writer.WriteHeader(tc.status)
+				writer.Write([]byte("<content>Foo</content>"))
+			}))
+			defer dummy.Close()
+
+			clock := newFakeClock()
+			templater := New(append(tc.options, WithClock(clock))...)
+			document := fmt.Sprintf(`<html><body><fragment src="%s">Bar</fragment></body></html>`, dummy.URL)
+
+			for _, step := range []struct {
+				advance  time.Duration
+				requests int32
+			}{
+				{requests: 1},
+				{advance: 59 * time.Second, requests: 1},
+				{advance: 2 * time.Second, requests: 2},
+			} {
+				clock.Advance(step.advance)
+				_, err := templater.Parse(strings.NewReader(document))
+				assert.NoError(t, err)
+				assert.Equal(t, step.requests, atomic.LoadInt32(&requests), "after %s", step.advance)
+			}
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package templating
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// coalesce shares a single in-flight request between all renders fetching the same fragment.
+// Every caller receives its own copy of the content, since the trees are mutated while splicing.
+// The shared request is detached from the render starting it, so its cancellation does not fail
+// the other renders, while each caller still stops waiting once its own context is done.
+func (t *Templater) coalesce(req *http.Request, node html.Node) (*fragmentResponse, error) {
+	if t.flights == nil {
+		return t.hedge(req, node)
+	}
+
+	results := t.flights.DoChan(t.requestKey(req), func() (interface{}, error) {
+		ctx, cancel := t.detach(req.Context(), node)
+		defer cancel()
+		return t.hedge(req.WithContext(ctx), node)
+	})
+
+	select {
+	case result := <-results:
+		if result.Err != nil {
+			return nil, result.Err
+		}
+		return result.Val.(*fragmentResponse).clone(), nil
+	case <-req.Context().Done():
+		return nil, req.Context().Err()
+	}
+}
+
+// detach returns a context keeping the values but not the cancellation of the context,
+// limited by the timeout of the fragment instead.
+func (t *Templater) detach(ctx context.Context, node html.Node) (context.Context, context.CancelFunc) {
+	detached := context.WithoutCancel(ctx)
+	if timeout, err := t.timeoutFor(node); err == nil && timeout > 0 {
+		return context.WithTimeout(detached, timeout)
+	}
+	return context.WithCancel(detached)
+}
+
+// requestKey identifies a fragment request by its method, normalized url and headers.
+// Headers differing for every render, like the composition token, are left out.
+func (t *Templater) requestKey(req *http.Request) string {
+	var builder strings.Builder
+	builder.WriteString(req.Method)
+	builder.WriteString(" ")
+	builder.WriteString(t.cacheKey(req.URL.String()))
+
+	names := make([]string, 0, len(req.Header))
+	for name := range req.Header {
+		if t.perRender(name) {
+			continue
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		builder.WriteString("\n")
+		builder.WriteString(name)
+		builder.WriteString(": ")
+		builder.WriteString(strings.Join(req.Header[name], ", "))
+	}
+	return builder.String()
+}
+
+// perRender reports whether the header is set to a value of its own for every render.
+func (t *Templater) perRender(name string) bool {
+	return name == compositionTokenHeader || (t.deadlineHeader != "" && name == http.CanonicalHeaderKey(t.deadlineHeader))
+}
+
+func cloneNode(node *html.Node) *html.Node {
+	clone := &html.Node{
+		Type:      node.Type,
+		DataAtom:  node.DataAtom,
+		Data:      node.Data,
+		Namespace: node.Namespace,
+		Attr:      append([]html.Attribute(nil), node.Attr...),
+	}
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		clone.AppendChild(cloneNode(child))
+	}
+	return clone
+}
@@ -0,0 +1,109 @@
+package templating
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/html"
+)
+
+func TestTemplater_Parse_RequestCoalescing(t *testing.T) {
+	var requests int32
+	dummy := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("Content-Type", "text/html")
+		atomic.AddInt32(&requests, 1)
+		time.Sleep(100 * time.Millisecond)
+		writer.Write([]byte("<content>Foo</content>"))
+	}))
+	defer dummy.Close()
+
+	templater := New(WithRequestCoalescing())
+
+	const expected = "<html><head></head><body><><content>Foo</content></></body></html>"
+	document := fmt.Sprintf(`<html><body><fragment src="%s"></fragment></body></html>`, dummy.URL)
+
+	var wg sync.WaitGroup
+	actual := make([]string, 2)
+	for i := range actual {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			actual[i], _ = templater.Parse(strings.NewReader(document))
+		}(i)
+	}
+	wg.Wait()
+
+	assert.Equal(t, []string{expected, expected}, actual)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&requests))
+
+	t.Run("should not fail other renders when the first is cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		go func() {
+			time.Sleep(50 * time.Millisecond)
+			cancel()
+		}()
+
+		var (
+			wg       sync.WaitGroup
+			leader   string
+			follower string
+		)
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			leader, _ = templater.ParseContext(ctx, strings.NewReader(document))
+		}()
+		go func() {
+			defer wg.Done()
+			time.Sleep(20 * time.Millisecond)
+			follower, _ = templater.Parse(strings.NewReader(document))
+		}()
+		wg.Wait()
+
+		assert.Equal(t, "<html><head></head><body><></></body></html>", leader)
+		assert.Equal(t, expected, follower)
+	})
+}
+
+func TestRequestKey(t *testing.T) {
+	first, _ := http.NewRequest(http.MethodGet, "https://example.com/nav", nil)
+	first.Header.Set("User-Agent", "foo")
+	second, _ := http.NewRequest(http.MethodGet, "https://example.com/nav", nil)
+	second.Header.Set("User-Agent", "bar")
+
+	var templater Templater
+	assert.Equal(t, templater.requestKey(first), templater.requestKey(first.Clone(first.Context())))
+	assert.NotEqual(t, templater.requestKey(first), templater.requestKey(second))
+
+	t.Run("should ignore headers set for every render", func(t *testing.T) {
+		templater := New(WithDeadlineHeader("X-Request-Timeout"))
+		other := first.Clone(first.Context())
+		first.Header.Set(compositionTokenHeader, "a")
+		first.Header.Set("X-Request-Timeout", "100")
+		other.Header.Set(compositionTokenHeader, "b")
+		other.Header.Set("X-Request-Timeout", "90")
+
+		assert.Equal(t, templater.requestKey(first), templater.requestKey(other))
+	})
+}
+
+func TestCloneNode(t *testing.T) {
+	original, _ := html.Parse(strings.NewReader(`<html><body><p class="a">Foo<b>Bar</b></p></body></html>`))
+	clone := cloneNode(original)
+
+	var expected, actual strings.Builder
+	html.Render(&expected, original)
+	html.Render(&actual, clone)
+	assert.Equal(t, expected.String(), actual.String())
+
+	clone.FirstChild.LastChild.FirstChild.Attr[0].Val = "b"
+	assert.Equal(t, "a", original.FirstChild.LastChild.FirstChild.Attr[0].Val)
+}
@@ -0,0 +1,77 @@
+package templating
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+const (
+	collapsedAttribute = "data-collapsed"
+)
+
+// collapse merges runs of adjacent fallbacks with identical content into their first element,
+// which counts the merged fallbacks in an attribute.
+func (t *Templater) collapse(c *composition) {
+	fallbacks := make(map[*html.Node]bool, len(c.fallbacks))
+	for _, node := range c.fallbacks {
+		fallbacks[node] = true
+	}
+
+	for _, node := range c.fallbacks {
+		if !fallbacks[node] || node.Parent == nil {
+			continue
+		}
+
+		head := node
+		for previous := adjacentElement(head, false); fallbacks[previous] && identical(previous, head); previous = adjacentElement(head, false) {
+			head = previous
+		}
+
+		count := 1
+		for next := adjacentElement(head, true); fallbacks[next] && identical(head, next); next = adjacentElement(head, true) {
+			for head.NextSibling != next {
+				head.Parent.RemoveChild(head.NextSibling)
+			}
+			head.Parent.RemoveChild(next)
+			delete(fallbacks, next)
+			count++
+		}
+		delete(fallbacks, head)
+
+		if count > 1 {
+			setAttribute(head, collapsedAttribute, strconv.Itoa(count))
+		}
+	}
+}
+
+// adjacentElement returns the next or previous sibling of the node, skipping whitespace.
+func adjacentElement(node *html.Node, next bool) *html.Node {
+	sibling := node.PrevSibling
+	if next {
+		sibling = node.NextSibling
+	}
+
+	for sibling != nil && sibling.Type == html.TextNode && strings.TrimSpace(sibling.Data) == "" {
+		if next {
+			sibling = sibling.NextSibling
+		} else {
+			sibling = sibling.PrevSibling
+		}
+	}
+	return sibling
+}
+
+// identical reports whether both nodes render to the same markup.
+func identical(a, b *html.Node) bool {
+	var left, right bytes.Buffer
+	if err := html.Render(&left, a); err != nil {
+		return false
+	}
+	if err := html.Render(&right, b); err != nil {
+		return false
+	}
+	return left.String() == right.String()
+}
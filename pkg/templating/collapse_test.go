@@ -0,0 +1,69 @@
+package templating
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTemplater_Parse_CollapsedFallbacks(t *testing.T) {
+	broken := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("Content-Type", "text/html")
+		writer.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer broken.Close()
+
+	dummy := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("Content-Type", "text/html")
+		writer.Write([]byte("<content>Foo</content>"))
+	}))
+	defer dummy.Close()
+
+	document := fmt.Sprintf(`<html><body><div>
+<fragment src="%[1]s">Unavailable</fragment>
+<fragment src="%[1]s">Unavailable</fragment>
+<fragment src="%[1]s">Unavailable</fragment>
+<fragment src="%[2]s">Unavailable</fragment>
+<fragment src="%[1]s">Unavailable</fragment>
+<fragment src="%[1]s">Other</fragment>
+</div></body></html>`, broken.URL, dummy.URL)
+
+	tt := map[string]struct {
+		options  []Option
+		expected string
+	}{
+		"collapsed": {
+			options: []Option{WithCollapsedFallbacks()},
+			expected: `<html><head></head><body><div>
+< data-collapsed="3">Unavailable</>
+<><content>Foo</content></>
+<>Unavailable</>
+<>Other</>
+</div></body></html>`,
+		},
+		"disabled": {
+			expected: `<html><head></head><body><div>
+<>Unavailable</>
+<>Unavailable</>
+<>Unavailable</>
+<><content>Foo</content></>
+<>Unavailable</>
+<>Other</>
+</div></body></html>`,
+		},
+	}
+
+	for name, tc := range tt {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			templater := New(tc.options...)
+			actual, err := templater.Parse(strings.NewReader(document))
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+}
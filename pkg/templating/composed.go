@@ -0,0 +1,52 @@
+package templating
+
+import (
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+const (
+	composedAttribute = "data-composed"
+)
+
+// markComposed marks the content of a resolved fragment with its source, so a later re-composition
+// of the document skips it, or reopens it from the source when forced. The source is exposed in the
+// markup as is. Content without a wrapper is wrapped in a div to carry the marker.
+func markComposed(content *html.Node, source string) {
+	if content.Data == "" {
+		content.Data, content.DataAtom = "div", atom.Div
+	}
+	setAttribute(content, composedAttribute, source)
+}
+
+// isComposed reports whether the node lies within a region marked as composed.
+func isComposed(node *html.Node) bool {
+	for parent := node.Parent; parent != nil; parent = parent.Parent {
+		if parent.Type != html.ElementNode {
+			continue
+		}
+		if _, ok := lookupAttribute(*parent, composedAttribute); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// reopenComposed turns all composed regions of the document back into fragments of their source,
+// keeping the composed content as fallback.
+func reopenComposed(root *html.Node, tag string) {
+	var regions []*html.Node
+	visit(root, func(node *html.Node) {
+		if node.Type != html.ElementNode {
+			return
+		}
+		if _, ok := lookupAttribute(*node, composedAttribute); ok {
+			regions = append(regions, node)
+		}
+	})
+
+	for _, region := range regions {
+		region.Data, region.DataAtom = tag, atom.Lookup([]byte(tag))
+		region.Attr = []html.Attribute{{Key: sourceAttribute, Val: attribute(*region, composedAttribute)}}
+	}
+}
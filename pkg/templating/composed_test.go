@@ -0,0 +1,62 @@
+package templating
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTemplater_Parse_ComposedMarker(t *testing.T) {
+	var requests int32
+	dummy := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("Content-Type", "text/html")
+		atomic.AddInt32(&requests, 1)
+		switch request.URL.Path {
+		case "/outer":
+			writer.Write([]byte(`<content>Outer<fragment src="/inner"></fragment></content>`))
+		default:
+			writer.Write([]byte("<content>" + strings.TrimPrefix(request.URL.Path, "/") + "</content>"))
+		}
+	}))
+	defer dummy.Close()
+
+	t.Run("should mark resolved fragments", func(t *testing.T) {
+		atomic.StoreInt32(&requests, 0)
+		expected := fmt.Sprintf(`<html><head></head><body><div data-composed="%[1]s/outer"><content>Outer<div data-composed="%[1]s/inner"><content>inner</content></div></content></div><>Bar</></body></html>`, dummy.URL)
+
+		templater := New(WithComposedMarker())
+		actual, err := templater.Parse(strings.NewReader(fmt.Sprintf(`<html><body><fragment src="%[1]s/outer"></fragment><fragment upstream="unknown">Bar</fragment></body></html>`, dummy.URL)))
+		assert.NoError(t, err)
+		assert.Equal(t, expected, actual)
+		assert.Equal(t, int32(2), atomic.LoadInt32(&requests))
+	})
+
+	document := fmt.Sprintf(`<html><body><section data-composed="%[1]s/old"><content>old</content><fragment src="%[1]s/skipped"></fragment></section><fragment src="%[1]s/new"></fragment></body></html>`, dummy.URL)
+
+	t.Run("should skip composed regions", func(t *testing.T) {
+		atomic.StoreInt32(&requests, 0)
+		expected := fmt.Sprintf(`<html><head></head><body><section data-composed="%[1]s/old"><content>old</content><fragment src="%[1]s/skipped"></fragment></section><div data-composed="%[1]s/new"><content>new</content></div></body></html>`, dummy.URL)
+
+		templater := New(WithComposedMarker())
+		actual, err := templater.Parse(strings.NewReader(document))
+		assert.NoError(t, err)
+		assert.Equal(t, expected, actual)
+		assert.Equal(t, int32(1), atomic.LoadInt32(&requests))
+	})
+
+	t.Run("should resolve composed regions again when forced", func(t *testing.T) {
+		atomic.StoreInt32(&requests, 0)
+		expected := fmt.Sprintf(`<html><head></head><body><div data-composed="%[1]s/new"><content>new</content></div><div data-composed="%[1]s/old"><content>old</content></div></body></html>`, dummy.URL)
+
+		templater := New(WithComposedMarker(), WithForcedRecomposition())
+		actual, err := templater.Parse(strings.NewReader(fmt.Sprintf(`<html><body><fragment src="%[1]s/new"></fragment><section data-composed="%[1]s/old"><content>stale</content></section></body></html>`, dummy.URL)))
+		assert.NoError(t, err)
+		assert.Equal(t, expected, actual)
+		assert.Equal(t, int32(2), atomic.LoadInt32(&requests))
+	})
+}
@@ -0,0 +1,136 @@
+package templating
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/html"
+)
+
+// composition holds the state shared by all fragments of a single render.
+// The state updated while resolving is guarded by mu, as fragments may resolve concurrently.
+type composition struct {
+	mu         sync.Mutex
+	id         string
+	start      time.Time
+	fragments  []FragmentReport
+	budgets    map[string]time.Duration
+	degraded   []string
+	failures   []FragmentError
+	depth      int
+	nested     []batch
+	etags      map[string]string
+	validators map[string]string
+	forward    http.Header
+	varyBy     []string
+	varied     bool
+	lang       string
+	settled    map[string]http.Header
+	origins    []string
+	token      string
+	fallbacks  []*html.Node
+	modules    []string
+	vars       map[string]string
+	hints      []html.Node
+	ctx        context.Context
+}
+
+func (t *Templater) newComposition(ctx context.Context) *composition {
+	var token string
+	if t.compositionToken != nil {
+		token = t.compositionToken()
+	}
+	return t.startComposition(ctx, token)
+}
+
+// startComposition returns a composition sending the given composition token with its requests.
+func (t *Templater) startComposition(ctx context.Context, token string) *composition {
+	budgets := make(map[string]time.Duration, len(t.groups))
+	for name, budget := range t.groups {
+		budgets[name] = budget
+	}
+
+	return &composition{
+		id:      newRequestID(),
+		start:   t.now(),
+		budgets: budgets,
+		settled: make(map[string]http.Header),
+		token:   token,
+		ctx:     ctx,
+	}
+}
+
+// budget returns the remaining time budget of the given timeout group.
+func (c *composition) budget(group string) (time.Duration, bool) {
+	if group == "" {
+		return 0, false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	budget, ok := c.budgets[group]
+	return budget, ok
+}
+
+// spend deducts the elapsed time from the budget of the given timeout group.
+func (c *composition) spend(group string, elapsed time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.budgets[group]; ok {
+		c.budgets[group] -= elapsed
+	}
+}
+
+// degrade records a fragment that fell back to its inline content after failing with the error.
+func (c *composition) degrade(fragment html.Node, err error) {
+	c.failures = append(c.failures, newFragmentError(fragment, err))
+	if id := attribute(fragment, idAttribute); id != "" {
+		c.degraded = append(c.degraded, id)
+	}
+}
+
+// markDegraded lists the ids of all fragments that fell back in an attribute of the body.
+func (t *Templater) markDegraded(c *composition, root *html.Node) {
+	if len(c.degraded) == 0 {
+		return
+	}
+
+	body, err := t.FindSection("body", root)
+	if err != nil {
+		return
+	}
+	setAttribute(body, degradedAttribute, strings.Join(c.degraded, " "))
+}
+
+// fellBack records the node rendered in place of a fragment that failed to resolve.
+func (c *composition) fellBack(fallback *html.Node) {
+	if fallback.Parent != nil {
+		c.fallbacks = append(c.fallbacks, fallback)
+	}
+}
+
+// settle records the response header of a fragment with an id, nil if it did not resolve.
+func (c *composition) settle(fragment html.Node, header http.Header) {
+	if id := attribute(fragment, idAttribute); id != "" {
+		c.settled[id] = header
+	}
+}
+
+// record adds the outcome of a fragment to the report of the render.
+func (c *composition) record(fragment FragmentReport) {
+	c.fragments = append(c.fragments, fragment)
+}
+
+// failed reports whether the render contained fragments and every one of them fell back.
+func (c *composition) failed() bool {
+	return len(c.fragments) > 0 && len(c.failures) == len(c.fragments)
+}
+
+func (c *composition) report(duration time.Duration) Report {
+	return Report{RequestID: c.id, Fragments: c.fragments, Duration: duration, Vary: c.varies()}
+}
@@ -0,0 +1,136 @@
+package templating
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/html"
+)
+
+func slowDummy(delay time.Duration, content string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("Content-Type", "text/html")
+		select {
+		case <-time.After(delay):
+		case <-request.Context().Done():
+			return
+		}
+		writer.Write([]byte(content))
+	}))
+}
+
+func TestTemplater_ParseWithNode_Timeout(t *testing.T) {
+	dummy := slowDummy(200*time.Millisecond, "<content>Foo</content>")
+	defer dummy.Close()
+
+	const expected = "<html><head></head><body><>Fallback</></body></html>"
+	root, _ := html.Parse(strings.NewReader(fmt.Sprintf(`<html><body><fragment src="%s">Fallback</fragment></body></html>`, dummy.URL)))
+
+	templater := New(WithTimeout(20 * time.Millisecond))
+	start := time.Now()
+	templater.ParseWithNode(root)
+
+	var actual bytes.Buffer
+	html.Render(&actual, root)
+	assert.Equal(t, expected, actual.String())
+	assert.Less(t, int64(time.Since(start)), int64(150*time.Millisecond))
+}
+
+func TestTemplater_ParseWithNode_TimeoutGroup(t *testing.T) {
+	first := slowDummy(80*time.Millisecond, "<content>First</content>")
+	defer first.Close()
+	second := slowDummy(80*time.Millisecond, "<content>Second</content>")
+	defer second.Close()
+	outsider := slowDummy(10*time.Millisecond, "<content>Outsider</content>")
+	defer outsider.Close()
+
+	root, _ := html.Parse(strings.NewReader(fmt.Sprintf(
+		`<html><body><fragment group="sidebar" src="%s">First fallback</fragment><fragment group="sidebar" src="%s">Second fallback</fragment><fragment src="%s">Outsider fallback</fragment></body></html>`,
+		first.URL, second.URL, outsider.URL,
+	)))
+
+	templater := New(WithTimeout(time.Second), WithTimeoutGroup("sidebar", 100*time.Millisecond))
+	start := time.Now()
+	templater.ParseWithNode(root)
+	elapsed := time.Since(start)
+
+	var actual bytes.Buffer
+	html.Render(&actual, root)
+	assert.Contains(t, []string{
+		"<html><head></head><body><><content>First</content></><>Second fallback</><><content>Outsider</content></></body></html>",
+		"<html><head></head><body><>First fallback</><><content>Second</content></><><content>Outsider</content></></body></html>",
+	}, actual.String())
+	assert.Less(t, int64(elapsed), int64(150*time.Millisecond), "the group member resolved last should only get the remaining budget")
+}
+
+func TestTemplater_ParseWithNode_DegradedMarker(t *testing.T) {
+	brokenDummy := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("Content-Type", "text/html")
+		writer.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer brokenDummy.Close()
+	dummy := slowDummy(0, "<content>Content</content>")
+	defer dummy.Close()
+
+	t.Run("should list the ids of the failed fragments", func(t *testing.T) {
+		root, _ := html.Parse(strings.NewReader(fmt.Sprintf(
+			`<html><body><fragment id="nav" src="%[1]s"></fragment><fragment id="main" src="%[2]s"></fragment><fragment id="footer" src="%[1]s"></fragment><fragment src="%[1]s"></fragment></body></html>`,
+			brokenDummy.URL, dummy.URL,
+		)))
+
+		templater := New(WithDegradedMarker())
+		templater.ParseWithNode(root)
+
+		body, _ := templater.FindSection("body", root)
+		assert.ElementsMatch(t, []string{"nav", "footer"}, strings.Fields(attribute(*body, "data-degraded")))
+	})
+
+	t.Run("should not mark a healthy composition", func(t *testing.T) {
+		root, _ := html.Parse(strings.NewReader(fmt.Sprintf(`<html><body><fragment id="main" src="%s"></fragment></body></html>`, dummy.URL)))
+
+		templater := New(WithDegradedMarker())
+		templater.ParseWithNode(root)
+
+		body, _ := templater.FindSection("body", root)
+		_, ok := lookupAttribute(*body, "data-degraded")
+		assert.False(t, ok)
+	})
+}
+
+func TestTemplater_Parse_CompositionToken(t *testing.T) {
+	var (
+		mu     sync.Mutex
+		tokens []string
+	)
+	dummy := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("Content-Type", "text/html")
+		mu.Lock()
+		tokens = append(tokens, request.Header.Get("X-Composition-Token"))
+		mu.Unlock()
+		writer.Write([]byte("<content>Foo</content>"))
+	}))
+	defer dummy.Close()
+
+	var renders int
+	templater := New(WithCompositionToken(func() string {
+		renders++
+		return fmt.Sprintf("token-%d", renders)
+	}))
+
+	document := fmt.Sprintf(`<html><body><fragment src="%[1]s"></fragment><fragment src="%[1]s"></fragment></body></html>`, dummy.URL)
+	for render := 1; render <= 2; render++ {
+		tokens = nil
+		_, err := templater.Parse(strings.NewReader(document))
+		assert.NoError(t, err)
+
+		expected := fmt.Sprintf("token-%d", render)
+		assert.Equal(t, []string{expected, expected}, tokens)
+	}
+}
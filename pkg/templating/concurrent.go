@@ -0,0 +1,74 @@
+package templating
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"golang.org/x/net/html"
+)
+
+// resolution is the outcome of resolving a fragment concurrently, applied to the tree afterwards.
+type resolution struct {
+	response *fragmentResponse
+	err      error
+	duration time.Duration
+}
+
+// spliceConcurrently resolves the fragments with at most the configured number of concurrent
+// requests, rendering the fallback of fragments waiting longer than the queue wait for a slot.
+// Once all completed, they are spliced in document order on the calling goroutine,
+// as the tree must not be mutated concurrently.
+func (t *Templater) spliceConcurrently(c *composition, root *html.Node, fragments []*html.Node) {
+	order := make(map[*html.Node]int, len(fragments))
+	visit(root, func(node *html.Node) {
+		order[node] = len(order)
+	})
+	sort.Slice(fragments, func(i, j int) bool {
+		return order[fragments[i]] < order[fragments[j]]
+	})
+
+	var (
+		wg      sync.WaitGroup
+		slots   = make(chan struct{}, t.maxConcurrency)
+		results = make([]resolution, len(fragments))
+	)
+	for i, element := range fragments {
+		i, node := i, *element
+
+		if err := t.enqueue(c.ctx, acquireSlot(slots)); err != nil {
+			results[i] = resolution{err: err}
+			continue
+		}
+
+		wg.Add(1)
+		go func() {
+			defer func() {
+				<-slots
+				wg.Done()
+			}()
+
+			start := t.now()
+			response, err := t.resolve(c, node)
+			results[i] = resolution{response: response, err: err, duration: t.since(start)}
+		}()
+	}
+	wg.Wait()
+
+	for i, element := range fragments {
+		t.apply(c, element, results[i].response, results[i].err, results[i].duration)
+	}
+}
+
+// acquireSlot returns a function taking one of the slots, or failing once the context is done.
+func acquireSlot(slots chan struct{}) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		select {
+		case slots <- struct{}{}:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
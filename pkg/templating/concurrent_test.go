@@ -0,0 +1,70 @@
+package templating
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTemplater_Parse_MaxConcurrency(t *testing.T) {
+	first := slowDummy(100*time.Millisecond, "<content>First</content>")
+	defer first.Close()
+	second := slowDummy(100*time.Millisecond, "<content>Second</content>")
+	defer second.Close()
+	third := slowDummy(100*time.Millisecond, "<content>Third</content>")
+	defer third.Close()
+
+	document := fmt.Sprintf(`<html><body><fragment src="%s"></fragment><div><fragment src="%s"></fragment></div><fragment src="%s"></fragment><fragment>Fallback</fragment></body></html>`, first.URL, second.URL, third.URL)
+	const expected = `<html><head></head><body><><content>First</content></><div><><content>Second</content></></div><><content>Third</content></><>Fallback</></body></html>`
+
+	tt := map[string]struct {
+		limit   int
+		atLeast time.Duration
+		atMost  time.Duration
+	}{
+		"all at once":   {limit: 3, atLeast: 100 * time.Millisecond, atMost: 250 * time.Millisecond},
+		"two at a time": {limit: 2, atLeast: 200 * time.Millisecond, atMost: 350 * time.Millisecond},
+		"one at a time": {limit: 1, atLeast: 300 * time.Millisecond, atMost: time.Second},
+	}
+
+	for name, tc := range tt {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			templater := New(WithMaxConcurrency(tc.limit))
+
+			start := time.Now()
+			actual, err := templater.Parse(strings.NewReader(document))
+			elapsed := time.Since(start)
+
+			assert.NoError(t, err)
+			assert.Equal(t, expected, actual)
+			assert.GreaterOrEqual(t, int64(elapsed), int64(tc.atLeast))
+			assert.Less(t, int64(elapsed), int64(tc.atMost))
+		})
+	}
+}
+
+func TestTemplater_Parse_MaxConcurrencyQueueWait(t *testing.T) {
+	first := slowDummy(200*time.Millisecond, "<content>First</content>")
+	defer first.Close()
+	second := slowDummy(0, "<content>Second</content>")
+	defer second.Close()
+
+	var audit bytes.Buffer
+	templater := New(WithMaxConcurrency(1), WithMaxQueueWait(30*time.Millisecond), WithAuditLog(&audit))
+	actual, err := templater.Parse(strings.NewReader(fmt.Sprintf(`<html><body><fragment src="%s"></fragment><fragment src="%s">Bar</fragment></body></html>`, first.URL, second.URL)))
+	assert.NoError(t, err)
+	assert.Equal(t, "<html><head></head><body><><content>First</content></><>Bar</></body></html>", actual)
+
+	var report Report
+	assert.NoError(t, json.Unmarshal(audit.Bytes(), &report))
+	if assert.Len(t, report.Fragments, 2) {
+		assert.Empty(t, report.Fragments[0].Error)
+		assert.Equal(t, ErrorQueueWait.Error(), report.Fragments[1].Error)
+	}
+}
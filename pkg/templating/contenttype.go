@@ -0,0 +1,39 @@
+package templating
+
+import (
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+var (
+	ErrorUnexpectedContentType = errors.New("fragment responded with an unexpected content type")
+)
+
+// defaultContentTypes are the media types fragment markup is accepted in unless configured otherwise.
+var defaultContentTypes = []string{"text/html", "application/xhtml+xml"}
+
+// checkContentType fails with ErrorUnexpectedContentType if the response is not of an accepted
+// media type. Fragments embedded as json or streamed as events bring their own type.
+func (t *Templater) checkContentType(header http.Header, as string) error {
+	if as == asJSONScript || as == asSSE {
+		return nil
+	}
+
+	mediaType, _, err := mime.ParseMediaType(header.Get("Content-Type"))
+	if err != nil {
+		mediaType = ""
+	}
+	accepted := t.contentTypes
+	if len(accepted) == 0 {
+		accepted = defaultContentTypes
+	}
+	for _, value := range accepted {
+		if strings.EqualFold(mediaType, value) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %q", ErrorUnexpectedContentType, mediaType)
+}
@@ -0,0 +1,82 @@
+package templating
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTemplater_Parse_ContentTypes(t *testing.T) {
+	dummy := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("Content-Type", request.URL.Query().Get("type"))
+		writer.Write([]byte(`<content>hello</content>`))
+	}))
+	defer dummy.Close()
+
+	testCases := map[string]struct {
+		options     []Option
+		contentType string
+		expected    string
+		err         error
+	}{
+		"html": {
+			contentType: "text/html; charset=utf-8",
+			expected:    `<html><head></head><body><><content>hello</content></></body></html>`,
+		},
+		"xhtml": {
+			contentType: "application/xhtml+xml",
+			expected:    `<html><head></head><body><><content>hello</content></></body></html>`,
+		},
+		"json": {
+			contentType: "application/json",
+			expected:    `<html><head></head><body><>Bar</></body></html>`,
+			err:         ErrorUnexpectedContentType,
+		},
+		"configured": {
+			options:     []Option{WithContentTypes("text/x-fragment")},
+			contentType: "text/x-fragment",
+			expected:    `<html><head></head><body><><content>hello</content></></body></html>`,
+		},
+		"not configured": {
+			options:     []Option{WithContentTypes("text/x-fragment")},
+			contentType: "text/html",
+			expected:    `<html><head></head><body><>Bar</></body></html>`,
+			err:         ErrorUnexpectedContentType,
+		},
+		"binary": {
+			contentType: "application/octet-stream",
+			expected:    `<html><head></head><body><>Bar</></body></html>`,
+			err:         ErrorUnexpectedContentType,
+		},
+		"defaults": {
+			options:     []Option{WithContentTypes()},
+			contentType: "application/xhtml+xml",
+			expected:    `<html><head></head><body><><content>hello</content></></body></html>`,
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			document := fmt.Sprintf(`<html><body><fragment src="%s?type=%s">Bar</fragment></body></html>`, dummy.URL, url.QueryEscape(tc.contentType))
+
+			templater := New(tc.options...)
+			actual, failures, err := templater.ParseWithResult(strings.NewReader(document))
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, actual)
+			if tc.err == nil {
+				assert.Empty(t, failures)
+				return
+			}
+			if assert.Len(t, failures, 1) {
+				assert.True(t, errors.Is(failures[0], tc.err))
+			}
+		})
+	}
+}
@@ -0,0 +1,96 @@
+package templating
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/html"
+)
+
+func TestTemplater_ParseContext_Cancel(t *testing.T) {
+	slow := slowDummy(time.Second, "<content>Slow</content>")
+	defer slow.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	var templater Templater
+	start := time.Now()
+	actual, err := templater.ParseContext(ctx, strings.NewReader(fmt.Sprintf(`<html><body><fragment src="%[1]s">Foo</fragment><fragment src="%[1]s">Bar</fragment></body></html>`, slow.URL)))
+	assert.NoError(t, err)
+	assert.Equal(t, "<html><head></head><body><>Foo</><>Bar</></body></html>", actual)
+	assert.Less(t, int64(time.Since(start)), int64(500*time.Millisecond))
+}
+
+func TestTemplater_ParseContext_Variants(t *testing.T) {
+	slow := slowDummy(time.Second, "<content>Slow</content>")
+	defer slow.Close()
+
+	document := fmt.Sprintf(`<html><body><fragment src="%s">Foo</fragment></body></html>`, slow.URL)
+	const expected = "<html><head></head><body><>Foo</></body></html>"
+
+	tt := map[string]func(ctx context.Context, templater Templater) (string, error){
+		"vars": func(ctx context.Context, templater Templater) (string, error) {
+			return templater.ParseWithVarsContext(ctx, strings.NewReader(document), nil)
+		},
+		"report": func(ctx context.Context, templater Templater) (string, error) {
+			actual, _, err := templater.ParseWithReportContext(ctx, strings.NewReader(document))
+			return actual, err
+		},
+		"result": func(ctx context.Context, templater Templater) (string, error) {
+			actual, _, err := templater.ParseWithResultContext(ctx, strings.NewReader(document))
+			return actual, err
+		},
+		"etags": func(ctx context.Context, templater Templater) (string, error) {
+			actual, _, err := templater.ParseWithETagsContext(ctx, strings.NewReader(document), nil)
+			return actual, err
+		},
+		"node report": func(ctx context.Context, templater Templater) (string, error) {
+			root, _ := html.Parse(strings.NewReader(document))
+			templater.ParseWithNodeReportContext(ctx, root)
+
+			var actual strings.Builder
+			err := html.Render(&actual, root)
+			return actual.String(), err
+		},
+	}
+
+	for name, parse := range tt {
+		parse := parse
+		t.Run(name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+
+			start := time.Now()
+			actual, err := parse(ctx, New())
+			assert.NoError(t, err)
+			assert.Equal(t, expected, actual)
+			assert.Less(t, int64(time.Since(start)), int64(500*time.Millisecond))
+		})
+	}
+}
+
+func TestTemplater_ResolveContext(t *testing.T) {
+	dummy := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("Content-Type", "text/html")
+		writer.Write([]byte("<content>Foo</content>"))
+	}))
+	defer dummy.Close()
+
+	fragment := html.Node{Data: fragmentIdentifier, Attr: []html.Attribute{{Key: "src", Val: dummy.URL}}}
+
+	var templater Templater
+	_, err := templater.ResolveContext(context.Background(), fragment)
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = templater.ResolveContext(ctx, fragment)
+	assert.ErrorIs(t, err, context.Canceled)
+}
@@ -0,0 +1,78 @@
+package templating
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	grpcTimeoutHeader = "grpc-timeout"
+	// grpcTimeoutDigits is the maximum number of digits of a grpc-timeout value.
+	grpcTimeoutDigits = 8
+)
+
+// deadlineKey is the context key of the deadline of a fragment by the clock of the templater.
+type deadlineKey struct{}
+
+// withTimeout returns a context done after the timeout like context.WithTimeout, also recording its
+// deadline by the clock of the templater, so the remaining time follows the clock like all other durations.
+func (t *Templater) withTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	deadline := t.now().Add(timeout)
+	if current, ok := t.deadline(ctx); ok && current.Before(deadline) {
+		deadline = current
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	return context.WithValue(ctx, deadlineKey{}, deadline), cancel
+}
+
+// deadline returns the deadline of the context by the clock of the templater. Deadlines not set by the
+// templater, e.g. of the caller, are converted by their remaining time.
+func (t *Templater) deadline(ctx context.Context) (time.Time, bool) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return time.Time{}, false
+	}
+
+	deadline = t.now().Add(time.Until(deadline))
+	if own, ok := ctx.Value(deadlineKey{}).(time.Time); ok && own.Before(deadline) {
+		return own, true
+	}
+	return deadline, true
+}
+
+// propagateDeadline sets the remaining time until the deadline of the context on the configured header,
+// so backends can shed work they could not complete in time.
+func (t *Templater) propagateDeadline(ctx context.Context, header http.Header) {
+	if t.deadlineHeader == "" {
+		return
+	}
+
+	deadline, ok := t.deadline(ctx)
+	if !ok {
+		return
+	}
+
+	remaining := deadline.Sub(t.now())
+	if remaining < time.Millisecond {
+		remaining = time.Millisecond
+	}
+	header.Set(t.deadlineHeader, formatDeadline(t.deadlineHeader, remaining))
+}
+
+// formatDeadline formats the remaining time in the grpc-timeout format for the grpc-timeout header,
+// in milliseconds for all others.
+func formatDeadline(name string, remaining time.Duration) string {
+	milliseconds := remaining.Milliseconds()
+	if !strings.EqualFold(name, grpcTimeoutHeader) {
+		return strconv.FormatInt(milliseconds, 10)
+	}
+
+	if value := strconv.FormatInt(milliseconds, 10); len(value) <= grpcTimeoutDigits {
+		return value + "m"
+	}
+	return strconv.FormatInt(int64(remaining/time.Second), 10) + "S"
+}
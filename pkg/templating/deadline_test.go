@@ -0,0 +1,104 @@
+package templating
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTemplater_Parse_DeadlineHeader(t *testing.T) {
+	tt := map[string]struct {
+		header  string
+		timeout time.Duration
+		parse   func(value string) (time.Duration, error)
+	}{
+		"grpc-timeout": {
+			header:  "grpc-timeout",
+			timeout: 500 * time.Millisecond,
+			parse: func(value string) (time.Duration, error) {
+				milliseconds, err := strconv.Atoi(strings.TrimSuffix(value, "m"))
+				return time.Duration(milliseconds) * time.Millisecond, err
+			},
+		},
+		"custom header": {
+			header:  "X-Request-Deadline",
+			timeout: 2 * time.Second,
+			parse: func(value string) (time.Duration, error) {
+				milliseconds, err := strconv.Atoi(value)
+				return time.Duration(milliseconds) * time.Millisecond, err
+			},
+		},
+	}
+
+	for name, tc := range tt {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			values := make(chan string, 1)
+			dummy := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+				writer.Header().Set("Content-Type", "text/html")
+				values <- request.Header.Get(tc.header)
+				writer.Write([]byte("<content>Foo</content>"))
+			}))
+			defer dummy.Close()
+
+			templater := New(WithTimeout(tc.timeout), WithDeadlineHeader(tc.header))
+			_, err := templater.Parse(strings.NewReader(fmt.Sprintf(`<html><body><fragment src="%s"></fragment></body></html>`, dummy.URL)))
+			assert.NoError(t, err)
+
+			remaining, err := tc.parse(<-values)
+			assert.NoError(t, err)
+			assert.LessOrEqual(t, int64(remaining), int64(tc.timeout))
+			assert.Greater(t, int64(remaining), int64(tc.timeout-100*time.Millisecond))
+		})
+	}
+}
+
+func TestTemplater_PropagateDeadline_Clock(t *testing.T) {
+	clock := newFakeClock()
+	templater := New(WithClock(clock), WithDeadlineHeader("X-Request-Deadline"))
+
+	t.Run("should follow the clock of the templater", func(t *testing.T) {
+		ctx, cancel := templater.withTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		clock.Advance(500 * time.Millisecond)
+
+		header := http.Header{}
+		templater.propagateDeadline(ctx, header)
+		assert.Equal(t, "1500", header.Get("X-Request-Deadline"))
+	})
+
+	t.Run("should convert the deadline of the caller", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		clock.Advance(time.Hour)
+
+		deadline, ok := templater.deadline(ctx)
+		assert.True(t, ok)
+		assert.InDelta(t, float64(time.Second), float64(deadline.Sub(clock.Now())), float64(100*time.Millisecond))
+	})
+}
+
+func TestFormatDeadline(t *testing.T) {
+	tt := []struct {
+		name      string
+		remaining time.Duration
+		expected  string
+	}{
+		{name: "grpc-timeout", remaining: 1500 * time.Millisecond, expected: "1500m"},
+		{name: "Grpc-Timeout", remaining: 1000 * time.Hour, expected: "3600000S"},
+		{name: "X-Deadline", remaining: 1500 * time.Millisecond, expected: "1500"},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, formatDeadline(tc.name, tc.remaining))
+		})
+	}
+}
@@ -0,0 +1,51 @@
+package templating
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// addErrorComment appends a comment listing the failed fragments of the composition to the body.
+func (t *Templater) addErrorComment(c *composition, root *html.Node) {
+	if len(c.failures) == 0 {
+		return
+	}
+
+	body, err := t.FindSection("body", root)
+	if err != nil {
+		return
+	}
+
+	summary := make([]string, 0, len(c.failures))
+	for _, failure := range c.failures {
+		summary = append(summary, describeFailure(failure))
+	}
+	body.AppendChild(&html.Node{
+		Type: html.CommentNode,
+		Data: " composition errors: " + strings.Join(summary, ", ") + " ",
+	})
+}
+
+// describeFailure names the failed fragment by its id, or its src without one, and the cause of the failure.
+func describeFailure(failure FragmentError) string {
+	name := failure.ID
+	if name == "" {
+		name = failure.Source
+	}
+
+	var reason string
+	switch {
+	case failure.StatusCode != 0:
+		reason = fmt.Sprint(failure.StatusCode)
+	case errors.Is(failure.Err, context.DeadlineExceeded):
+		reason = "timeout"
+	default:
+		reason = failure.Err.Error()
+	}
+	// a comment must not contain --, which could end it early
+	return strings.ReplaceAll(fmt.Sprintf("%s(%s)", name, reason), "--", "- -")
+}
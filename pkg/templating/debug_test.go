@@ -0,0 +1,51 @@
+package templating
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTemplater_Parse_DebugErrorComment(t *testing.T) {
+	dummy := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("Content-Type", "text/html")
+		switch request.URL.Path {
+		case "/nav":
+			writer.WriteHeader(http.StatusInternalServerError)
+		case "/footer":
+			time.Sleep(100 * time.Millisecond)
+		default:
+			writer.Write([]byte("<content>hello</content>"))
+		}
+	}))
+	defer dummy.Close()
+
+	testCases := map[string]struct {
+		document string
+		expected string
+	}{
+		"failures": {
+			document: `<html><body><fragment id="nav" src="%[1]s/nav">Nav</fragment><fragment id="footer" src="%[1]s/footer" timeout="20ms">Footer</fragment></body></html>`,
+			expected: `<html><head></head><body><>Nav</><>Footer</><!-- composition errors: nav(500), footer(timeout) --></body></html>`,
+		},
+		"clean": {
+			document: `<html><body><fragment id="teaser" src="%[1]s/teaser"></fragment></body></html>`,
+			expected: `<html><head></head><body><><content>hello</content></></body></html>`,
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			templater := New(WithDebugErrorComment())
+			actual, err := templater.Parse(strings.NewReader(fmt.Sprintf(tc.document, dummy.URL)))
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+}
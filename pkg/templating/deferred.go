@@ -0,0 +1,97 @@
+package templating
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+const (
+	deferredAttribute = "data-fragment-src"
+	pageAttribute     = "data-page"
+	regionAttribute   = "data-server-count"
+	loadingAttribute  = "loading"
+	asyncAttribute    = "async"
+)
+
+var (
+	ErrorClientRender = errors.New("fragment must be rendered client-side")
+)
+
+// rendersClient reports whether the response header carries the configured client render signal.
+func (t *Templater) rendersClient(header http.Header) bool {
+	if t.clientRenderHeader == "" {
+		return false
+	}
+	return strings.EqualFold(strings.TrimSpace(header.Get(t.clientRenderHeader)), t.clientRenderValue)
+}
+
+// deferred replaces the fragment element by a placeholder resolved client-side. The placeholder
+// keeps the inline content of the fragment and exposes its src to the hydrating script.
+func deferred(element *html.Node) *html.Node {
+	placeholder := &html.Node{
+		Type:     html.ElementNode,
+		DataAtom: atom.Div,
+		Data:     "div",
+		Attr:     []html.Attribute{{Key: deferredAttribute, Val: attribute(*element, sourceAttribute)}},
+	}
+	if id := attribute(*element, idAttribute); id != "" {
+		placeholder.Attr = append(placeholder.Attr, html.Attribute{Key: idAttribute, Val: id})
+	}
+
+	for child := element.FirstChild; child != nil; child = element.FirstChild {
+		element.RemoveChild(child)
+		placeholder.AppendChild(child)
+	}
+
+	if element.Parent != nil {
+		element.Parent.InsertBefore(placeholder, element)
+		element.Parent.RemoveChild(element)
+	}
+	return placeholder
+}
+
+// lazy reports whether the fragment opts out of server-side composition with loading="lazy" or async.
+func lazy(node html.Node) bool {
+	if strings.EqualFold(attribute(node, loadingAttribute), "lazy") {
+		return true
+	}
+	_, ok := lookupAttribute(node, asyncAttribute)
+	return ok
+}
+
+// paginate defers all but the first fragments of a region, an element with a data-server-count
+// attribute. The deferred fragments are numbered in pages of that size for client-side infinite scrolling.
+func paginate(root *html.Node, tag string) {
+	var regions []*html.Node
+	visit(root, func(node *html.Node) {
+		if node.Type == html.ElementNode {
+			if _, ok := lookupAttribute(*node, regionAttribute); ok {
+				regions = append(regions, node)
+			}
+		}
+	})
+
+	for _, region := range regions {
+		count, err := strconv.Atoi(attribute(*region, regionAttribute))
+		if err != nil || count < 1 {
+			continue
+		}
+
+		var fragments []*html.Node
+		visit(region, func(node *html.Node) {
+			if node.Type == html.ElementNode && node.Data == tag {
+				fragments = append(fragments, node)
+			}
+		})
+
+		for i := count; i < len(fragments); i++ {
+			placeholder := deferred(fragments[i])
+			placeholder.Attr = append(placeholder.Attr, html.Attribute{Key: pageAttribute, Val: strconv.Itoa(i / count)})
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package templating
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTemplater_Parse_Region(t *testing.T) {
+	var requests int32
+	dummy := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("Content-Type", "text/html")
+		atomic.AddInt32(&requests, 1)
+		writer.Write([]byte(fmt.Sprintf("<content>%s</content>", request.URL.Path)))
+	}))
+	defer dummy.Close()
+
+	var fragments, expected strings.Builder
+	for i := 0; i < 10; i++ {
+		fragments.WriteString(fmt.Sprintf(`<fragment src="%s/%d">Loading</fragment>`, dummy.URL, i))
+		if i < 3 {
+			expected.WriteString(fmt.Sprintf("<><content>/%d</content></>", i))
+			continue
+		}
+		expected.WriteString(fmt.Sprintf(`<div data-fragment-src="%s/%d" data-page="%d">Loading</div>`, dummy.URL, i, i/3))
+	}
+
+	templater := New()
+	actual, err := templater.Parse(strings.NewReader(fmt.Sprintf(`<html><body><ul data-server-count="3">%s</ul></body></html>`, fragments.String())))
+	assert.NoError(t, err)
+	assert.Equal(t, fmt.Sprintf(`<html><head></head><body><ul data-server-count="3">%s</ul></body></html>`, expected.String()), actual)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&requests))
+}
+
+func TestTemplater_Parse_ClientRenderSignal(t *testing.T) {
+	dummy := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("Content-Type", "text/html")
+		if request.URL.Path == "/account" {
+			writer.Header().Set("X-Render", "client")
+		}
+		writer.Write([]byte("<content>Foo</content>"))
+	}))
+	defer dummy.Close()
+
+	document := fmt.Sprintf(`<html><body><fragment id="account" src="%[1]s/account">Loading</fragment><fragment src="%[1]s/teaser">Bar</fragment></body></html>`, dummy.URL)
+
+	t.Run("should defer fragments signalling a client render", func(t *testing.T) {
+		expected := fmt.Sprintf(`<html><head></head><body><div data-fragment-src="%s/account" id="account">Loading</div><><content>Foo</content></></body></html>`, dummy.URL)
+
+		templater := New(WithClientRenderSignal("X-Render", "client"))
+		actual, err := templater.Parse(strings.NewReader(document))
+		assert.NoError(t, err)
+		assert.Equal(t, expected, actual)
+	})
+
+	t.Run("should splice the content without the signal configured", func(t *testing.T) {
+		const expected = `<html><head></head><body><><content>Foo</content></><><content>Foo</content></></body></html>`
+
+		templater := New()
+		actual, err := templater.Parse(strings.NewReader(document))
+		assert.NoError(t, err)
+		assert.Equal(t, expected, actual)
+	})
+}
+
+func TestTemplater_Parse_RuntimeScript(t *testing.T) {
+	dummy := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("Content-Type", "text/html")
+		writer.Write([]byte(fmt.Sprintf("<content>%s</content>", request.URL.Path)))
+	}))
+	defer dummy.Close()
+
+	testCases := map[string]struct {
+		document string
+		expected string
+	}{
+		"deferred fragments": {
+			document: `<html><body><ul data-server-count="1"><fragment src="{origin}/0">Loading</fragment><fragment src="{origin}/1">Loading</fragment><fragment src="{origin}/2">Loading</fragment></ul></body></html>`,
+			expected: `<html><head><script src="/runtime.js"></script></head><body><ul data-server-count="1"><><content>/0</content></><div data-fragment-src="{origin}/1" data-page="1">Loading</div><div data-fragment-src="{origin}/2" data-page="2">Loading</div></ul></body></html>`,
+		},
+		"already present": {
+			document: `<html><head><script src="/runtime.js"></script></head><body><ul data-server-count="1"><fragment src="{origin}/0">Loading</fragment><fragment src="{origin}/1">Loading</fragment></ul></body></html>`,
+			expected: `<html><head><script src="/runtime.js"></script></head><body><ul data-server-count="1"><><content>/0</content></><div data-fragment-src="{origin}/1" data-page="1">Loading</div></ul></body></html>`,
+		},
+		"without deferred fragments": {
+			document: `<html><body><fragment src="{origin}/0">Loading</fragment></body></html>`,
+			expected: `<html><head></head><body><><content>/0</content></></body></html>`,
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			templater := New(WithRuntimeScript("/runtime.js"))
+			actual, err := templater.Parse(strings.NewReader(strings.ReplaceAll(tc.document, "{origin}", dummy.URL)))
+			assert.NoError(t, err)
+			assert.Equal(t, strings.ReplaceAll(tc.expected, "{origin}", dummy.URL), actual)
+		})
+	}
+}
+
+func TestTemplater_Parse_Lazy(t *testing.T) {
+	var requests int32
+	dummy := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("Content-Type", "text/html")
+		atomic.AddInt32(&requests, 1)
+		writer.Write([]byte("<content>Foo</content>"))
+	}))
+	defer dummy.Close()
+
+	testCases := map[string]struct {
+		attributes string
+		expected   string
+		requests   int32
+	}{
+		"loading lazy": {
+			attributes: ` loading="lazy"`,
+			expected:   `<html><head></head><body><div data-fragment-src="{origin}/reviews" id="reviews">Loading</div></body></html>`,
+		},
+		"async": {
+			attributes: ` async`,
+			expected:   `<html><head></head><body><div data-fragment-src="{origin}/reviews" id="reviews">Loading</div></body></html>`,
+		},
+		"loading eager": {
+			attributes: ` loading="eager"`,
+			expected:   `<html><head></head><body><><content>Foo</content></></body></html>`,
+			requests:   1,
+		},
+		"synchronous by default": {
+			expected: `<html><head></head><body><><content>Foo</content></></body></html>`,
+			requests: 1,
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			atomic.StoreInt32(&requests, 0)
+
+			templater := New()
+			actual, err := templater.Parse(strings.NewReader(fmt.Sprintf(`<html><body><fragment id="reviews" src="%s/reviews"%s>Loading</fragment></body></html>`, dummy.URL, tc.attributes)))
+			assert.NoError(t, err)
+			assert.Equal(t, strings.ReplaceAll(tc.expected, "{origin}", dummy.URL), actual)
+			assert.Equal(t, tc.requests, atomic.LoadInt32(&requests))
+		})
+	}
+}
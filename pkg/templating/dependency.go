@@ -0,0 +1,73 @@
+package templating
+
+import (
+	"net/http"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// spliceDependents splices the fragments depending on another one, once their dependency is settled.
+// A dependent fragment only renders when its dependency resolved and returned the header of its
+// when-header condition, e.g. when-header="X-Variant: b". Otherwise it is removed.
+func (t *Templater) spliceDependents(c *composition, dependents []*html.Node) {
+	for len(dependents) > 0 {
+		waiting := make(map[string]bool, len(dependents))
+		for _, element := range dependents {
+			if id := attribute(*element, idAttribute); id != "" {
+				waiting[id] = true
+			}
+		}
+
+		var pending []*html.Node
+		for _, element := range dependents {
+			dependency := attribute(*element, dependsOnAttribute)
+			if _, settled := c.settled[dependency]; !settled && waiting[dependency] {
+				pending = append(pending, element)
+				continue
+			}
+
+			if c.satisfies(*element) {
+				t.splice(c, element)
+				continue
+			}
+			c.settle(*element, nil)
+			element.Parent.RemoveChild(element)
+		}
+
+		if len(pending) == len(dependents) {
+			// the remaining fragments depend on each other and can never be settled
+			for _, element := range pending {
+				element.Parent.RemoveChild(element)
+			}
+			return
+		}
+		dependents = pending
+	}
+}
+
+// satisfies reports whether the dependency of the fragment resolved and matches its header condition.
+func (c *composition) satisfies(fragment html.Node) bool {
+	header := c.settled[attribute(fragment, dependsOnAttribute)]
+	if header == nil {
+		return false
+	}
+
+	condition := attribute(fragment, whenHeaderAttribute)
+	if condition == "" {
+		return true
+	}
+
+	name, expected, hasValue := strings.Cut(condition, ":")
+	values, ok := header[http.CanonicalHeaderKey(strings.TrimSpace(name))]
+	if !ok || !hasValue {
+		return ok
+	}
+
+	for _, value := range values {
+		if strings.TrimSpace(value) == strings.TrimSpace(expected) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,79 @@
+package templating
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTemplater_Parse_DependsOn(t *testing.T) {
+	experiment := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("Content-Type", "text/html")
+		writer.Header().Set("X-Variant", "b")
+		writer.Write([]byte("<content>Experiment</content>"))
+	}))
+	defer experiment.Close()
+
+	brokenDummy := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("Content-Type", "text/html")
+		writer.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer brokenDummy.Close()
+
+	var requests int32
+	dummy := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("Content-Type", "text/html")
+		atomic.AddInt32(&requests, 1)
+		writer.Write([]byte(fmt.Sprintf("<content>%s</content>", request.URL.Path)))
+	}))
+	defer dummy.Close()
+
+	tt := []struct {
+		fragment string
+		expected string
+		requests int32
+	}{
+		{
+			fragment: `<fragment depends-on="ab" when-header="X-Variant: b" src="%s/b">Fallback</fragment>`,
+			expected: "<><content>/b</content></>",
+			requests: 1,
+		},
+		{
+			fragment: `<fragment depends-on="ab" when-header="X-Variant: a" src="%s/a">Fallback</fragment>`,
+			expected: "",
+		},
+		{
+			fragment: `<fragment depends-on="ab" when-header="X-Variant" src="%s/any">Fallback</fragment>`,
+			expected: "<><content>/any</content></>",
+			requests: 1,
+		},
+		{
+			fragment: `<fragment depends-on="broken" src="%s/broken">Fallback</fragment>`,
+			expected: "",
+		},
+		{
+			fragment: `<fragment id="first" depends-on="second" src="%[1]s/first"></fragment><fragment id="second" depends-on="first" src="%[1]s/second"></fragment>`,
+			expected: "",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run("", func(t *testing.T) {
+			atomic.StoreInt32(&requests, 0)
+
+			templater := New()
+			actual, err := templater.Parse(strings.NewReader(fmt.Sprintf(
+				`<html><body>%s<fragment id="ab" src="%s"></fragment><fragment id="broken" src="%s"></fragment></body></html>`,
+				fmt.Sprintf(tc.fragment, dummy.URL), experiment.URL, brokenDummy.URL,
+			)))
+			assert.NoError(t, err)
+			assert.Equal(t, fmt.Sprintf("<html><head></head><body>%s<><content>Experiment</content></><></></body></html>", tc.expected), actual)
+			assert.Equal(t, tc.requests, atomic.LoadInt32(&requests))
+		})
+	}
+}
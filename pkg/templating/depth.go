@@ -0,0 +1,35 @@
+package templating
+
+import (
+	"errors"
+
+	"golang.org/x/net/html"
+)
+
+const (
+	defaultMaxDepth = 5
+	depthComment    = " fragment depth limit reached "
+)
+
+var (
+	ErrorMaxDepth = errors.New("fragment nesting exceeds the maximum depth")
+)
+
+// depthLimit returns the maximum nesting depth of fragments, defaulting to defaultMaxDepth.
+func (t *Templater) depthLimit() int {
+	if t.maxDepth > 0 {
+		return t.maxDepth
+	}
+	return defaultMaxDepth
+}
+
+// exceedDepth renders the fallback of fragments nested beyond the maximum depth without
+// requesting them, which terminates fragments that embed each other.
+func (t *Templater) exceedDepth(c *composition, fragments []*html.Node) {
+	for _, element := range fragments {
+		if t.depthComment {
+			element.InsertBefore(&html.Node{Type: html.CommentNode, Data: depthComment}, element.FirstChild)
+		}
+		t.apply(c, element, nil, ErrorMaxDepth, 0)
+	}
+}
@@ -0,0 +1,63 @@
+package templating
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTemplater_Parse_MaxDepth(t *testing.T) {
+	var requests int32
+	var first, second *httptest.Server
+	first = httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("Content-Type", "text/html")
+		atomic.AddInt32(&requests, 1)
+		fmt.Fprintf(writer, `<content>a<fragment src="%s">no b</fragment></content>`, second.URL)
+	}))
+	defer first.Close()
+	second = httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("Content-Type", "text/html")
+		atomic.AddInt32(&requests, 1)
+		fmt.Fprintf(writer, `<content>b<fragment src="%s">no a</fragment></content>`, first.URL)
+	}))
+	defer second.Close()
+
+	testCases := map[string]struct {
+		options  []Option
+		expected string
+		requests int32
+	}{
+		"default depth": {
+			expected: "<html><head></head><body><><content>a<><content>b<><content>a<><content>b<><content>a<>no b</></content></></content></></content></></content></></content></></body></html>",
+			requests: 5,
+		},
+		"configured depth": {
+			options:  []Option{WithMaxDepth(2)},
+			expected: "<html><head></head><body><><content>a<><content>b<>no a</></content></></content></></body></html>",
+			requests: 2,
+		},
+		"depth comment": {
+			options:  []Option{WithMaxDepth(1), WithDepthComment()},
+			expected: "<html><head></head><body><><content>a<><!-- fragment depth limit reached -->no b</></content></></body></html>",
+			requests: 1,
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			atomic.StoreInt32(&requests, 0)
+
+			templater := New(tc.options...)
+			actual, err := templater.Parse(strings.NewReader(fmt.Sprintf(`<html><body><fragment src="%s"></fragment></body></html>`, first.URL)))
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, actual)
+			assert.Equal(t, tc.requests, atomic.LoadInt32(&requests))
+		})
+	}
+}
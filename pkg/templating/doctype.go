@@ -0,0 +1,31 @@
+package templating
+
+import (
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// setDoctype replaces the doctype of the document by the configured one, adding it to documents
+// without any. Documents keep their doctype if the configured one is not a valid declaration.
+func (t *Templater) setDoctype(root *html.Node) {
+	if root.Type != html.DocumentNode {
+		return
+	}
+
+	parsed, err := html.Parse(strings.NewReader(t.doctype))
+	if err != nil || parsed.FirstChild == nil || parsed.FirstChild.Type != html.DoctypeNode {
+		return
+	}
+	doctype := parsed.FirstChild
+	parsed.RemoveChild(doctype)
+
+	for child := root.FirstChild; child != nil; {
+		next := child.NextSibling
+		if child.Type == html.DoctypeNode {
+			root.RemoveChild(child)
+		}
+		child = next
+	}
+	root.InsertBefore(doctype, root.FirstChild)
+}
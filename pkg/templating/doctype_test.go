@@ -0,0 +1,51 @@
+package templating
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTemplater_Parse_Doctype(t *testing.T) {
+	testCases := map[string]struct {
+		doctype  string
+		input    string
+		expected string
+	}{
+		"added to documents without a doctype": {
+			doctype:  "<!DOCTYPE html>",
+			input:    `<html><body>Foo</body></html>`,
+			expected: `<!DOCTYPE html><html><head></head><body>Foo</body></html>`,
+		},
+		"replacing the doctype of the input": {
+			doctype:  "<!DOCTYPE html>",
+			input:    `<!DOCTYPE html PUBLIC "-//W3C//DTD HTML 4.01//EN" "http://www.w3.org/TR/html4/strict.dtd"><html><body>Foo</body></html>`,
+			expected: `<!DOCTYPE html><html><head></head><body>Foo</body></html>`,
+		},
+		"custom": {
+			doctype:  `<!DOCTYPE html PUBLIC "-//W3C//DTD XHTML 1.0 Strict//EN" "http://www.w3.org/TR/xhtml1/DTD/xhtml1-strict.dtd">`,
+			input:    `<html><body>Foo</body></html>`,
+			expected: `<!DOCTYPE html PUBLIC "-//W3C//DTD XHTML 1.0 Strict//EN" "http://www.w3.org/TR/xhtml1/DTD/xhtml1-strict.dtd"><html><head></head><body>Foo</body></html>`,
+		},
+		"preserving the input": {
+			input:    `<!DOCTYPE html><html><body>Foo</body></html>`,
+			expected: `<!DOCTYPE html><html><head></head><body>Foo</body></html>`,
+		},
+		"invalid": {
+			doctype:  "html",
+			input:    `<html><body>Foo</body></html>`,
+			expected: `<html><head></head><body>Foo</body></html>`,
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			templater := New(WithDoctype(tc.doctype))
+			actual, err := templater.Parse(strings.NewReader(tc.input))
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+}
@@ -0,0 +1,324 @@
+package templating
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+type cssRule struct {
+	selectors   []cssSelector
+	declaration []cssDeclaration
+}
+
+type cssSelector struct {
+	tag         string
+	id          string
+	classes     []string
+	specificity int
+}
+
+type cssDeclaration struct {
+	property string
+	value    string
+}
+
+type cssMatch struct {
+	specificity int
+	order       int
+	declaration []cssDeclaration
+}
+
+// inlineStyles prepares a composed document for email clients: the rules of all style elements and
+// linked stylesheets are inlined into the style attributes of the matching elements and scripts are removed.
+// Relative stylesheet urls of the page are resolved against its base element.
+func (t *Templater) inlineStyles(ctx context.Context, root *html.Node) {
+	var (
+		base     string
+		rules    []cssRule
+		obsolete []*html.Node
+	)
+	visit(root, func(node *html.Node) {
+		if node.Type != html.ElementNode {
+			return
+		}
+
+		switch node.DataAtom {
+		case atom.Base:
+			if base == "" {
+				base = attribute(*node, "href")
+			}
+		case atom.Style:
+			rules = append(rules, parseStylesheet(textContent(node))...)
+			obsolete = append(obsolete, node)
+		case atom.Link:
+			if !strings.EqualFold(attribute(*node, "rel"), "stylesheet") {
+				return
+			}
+			resolveLink(base, *node)
+			if stylesheet, err := t.fetchStylesheet(ctx, attribute(*node, "href")); err == nil {
+				rules = append(rules, parseStylesheet(stylesheet)...)
+			}
+			obsolete = append(obsolete, node)
+		case atom.Script:
+			obsolete = append(obsolete, node)
+		}
+	})
+
+	for _, node := range obsolete {
+		if node.Parent != nil {
+			node.Parent.RemoveChild(node)
+		}
+	}
+
+	visit(root, func(node *html.Node) {
+		if node.Type != html.ElementNode {
+			return
+		}
+
+		var matches []cssMatch
+		for i, rule := range rules {
+			specificity := -1
+			for _, selector := range rule.selectors {
+				if selector.matches(node) && selector.specificity > specificity {
+					specificity = selector.specificity
+				}
+			}
+			if specificity >= 0 {
+				matches = append(matches, cssMatch{specificity: specificity, order: i, declaration: rule.declaration})
+			}
+		}
+		if len(matches) == 0 {
+			return
+		}
+
+		sort.SliceStable(matches, func(i, j int) bool {
+			return matches[i].specificity < matches[j].specificity
+		})
+
+		var declarations []cssDeclaration
+		for _, match := range matches {
+			declarations = append(declarations, match.declaration...)
+		}
+		inline, _ := lookupAttribute(*node, "style")
+		declarations = append(declarations, parseDeclarations(inline)...)
+
+		setAttribute(node, "style", renderDeclarations(declarations))
+	})
+}
+
+// absolutizeStylesheets resolves relative hrefs of stylesheets linked by the fragment against its url,
+// as they are fetched for inlining once the fragment is part of the page.
+func absolutizeStylesheets(source string, content *html.Node) {
+	visit(content, func(node *html.Node) {
+		if node.DataAtom == atom.Link && strings.EqualFold(attribute(*node, "rel"), "stylesheet") {
+			resolveLink(source, *node)
+		}
+	})
+}
+
+func (t *Templater) fetchStylesheet(ctx context.Context, href string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, href, nil)
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := t.httpClient().Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", errors.New("could not fetch the stylesheet")
+	}
+
+	content, err := io.ReadAll(newLimitedReader(resp.Body, t.responseLimit()))
+	return string(content), err
+}
+
+// parseStylesheet parses the plain rules of a stylesheet. At-rules and rules whose
+// selectors can not be inlined, like combinators or pseudo classes, are skipped.
+func parseStylesheet(stylesheet string) []cssRule {
+	stylesheet = stripComments(stylesheet)
+
+	var rules []cssRule
+	for len(stylesheet) > 0 {
+		open := strings.IndexByte(stylesheet, '{')
+		if open < 0 {
+			break
+		}
+
+		prelude := strings.TrimSpace(stylesheet[:open])
+		end := blockEnd(stylesheet, open)
+		block := stylesheet[open+1 : end]
+		if end < len(stylesheet) {
+			end++
+		}
+		stylesheet = stylesheet[end:]
+
+		if strings.HasPrefix(prelude, "@") {
+			continue
+		}
+
+		var selectors []cssSelector
+		for _, value := range strings.Split(prelude, ",") {
+			if selector, ok := parseSelector(strings.TrimSpace(value)); ok {
+				selectors = append(selectors, selector)
+			}
+		}
+		if len(selectors) == 0 {
+			continue
+		}
+
+		rules = append(rules, cssRule{selectors: selectors, declaration: parseDeclarations(block)})
+	}
+	return rules
+}
+
+func stripComments(stylesheet string) string {
+	for {
+		start := strings.Index(stylesheet, "/*")
+		if start < 0 {
+			return stylesheet
+		}
+
+		end := strings.Index(stylesheet[start+2:], "*/")
+		if end < 0 {
+			return stylesheet[:start]
+		}
+		stylesheet = stylesheet[:start] + stylesheet[start+2+end+2:]
+	}
+}
+
+// blockEnd returns the index of the brace closing the block opened at the given index.
+func blockEnd(stylesheet string, open int) int {
+	depth := 0
+	for i := open; i < len(stylesheet); i++ {
+		switch stylesheet[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return len(stylesheet)
+}
+
+func parseSelector(value string) (cssSelector, bool) {
+	if value == "" || strings.ContainsAny(value, " >+~:[") {
+		return cssSelector{}, false
+	}
+
+	var selector cssSelector
+	for len(value) > 0 {
+		next := strings.IndexAny(value[1:], "#.") + 1
+		if next == 0 {
+			next = len(value)
+		}
+
+		part := value[:next]
+		value = value[next:]
+		switch part[0] {
+		case '#':
+			selector.id = part[1:]
+			selector.specificity += 100
+		case '.':
+			selector.classes = append(selector.classes, part[1:])
+			selector.specificity += 10
+		case '*':
+		default:
+			selector.tag = strings.ToLower(part)
+			selector.specificity++
+		}
+	}
+	return selector, true
+}
+
+func (s cssSelector) matches(node *html.Node) bool {
+	if s.tag != "" && s.tag != node.Data {
+		return false
+	}
+
+	if s.id != "" && s.id != attribute(*node, "id") {
+		return false
+	}
+
+	classes := strings.Fields(attribute(*node, "class"))
+	for _, class := range s.classes {
+		found := false
+		for _, candidate := range classes {
+			if candidate == class {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+func parseDeclarations(block string) []cssDeclaration {
+	var declarations []cssDeclaration
+	for _, value := range strings.Split(block, ";") {
+		property, value, ok := strings.Cut(value, ":")
+		property = strings.ToLower(strings.TrimSpace(property))
+		if !ok || property == "" {
+			continue
+		}
+
+		declarations = append(declarations, cssDeclaration{property: property, value: strings.TrimSpace(value)})
+	}
+	return declarations
+}
+
+// renderDeclarations renders the declarations as a style attribute, later declarations win.
+func renderDeclarations(declarations []cssDeclaration) string {
+	var (
+		properties []string
+		values     = make(map[string]string)
+	)
+	for _, declaration := range declarations {
+		if _, ok := values[declaration.property]; !ok {
+			properties = append(properties, declaration.property)
+		}
+		values[declaration.property] = declaration.value
+	}
+
+	result := make([]string, 0, len(properties))
+	for _, property := range properties {
+		result = append(result, property+": "+values[property])
+	}
+	return strings.Join(result, "; ")
+}
+
+func textContent(node *html.Node) string {
+	var builder strings.Builder
+	visit(node, func(node *html.Node) {
+		if node.Type == html.TextNode {
+			builder.WriteString(node.Data)
+		}
+	})
+	return builder.String()
+}
+
+func setAttribute(node *html.Node, key, value string) {
+	for i, attribute := range node.Attr {
+		if attribute.Key == key {
+			node.Attr[i].Val = value
+			return
+		}
+	}
+	node.Attr = append(node.Attr, html.Attribute{Key: key, Val: value})
+}
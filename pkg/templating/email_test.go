@@ -0,0 +1,76 @@
+package templating
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTemplater_Parse_EmailMode(t *testing.T) {
+	stylesheet := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("Content-Type", "text/css")
+		writer.Write([]byte(`a { color: blue; text-decoration: none }`))
+	}))
+	defer stylesheet.Close()
+
+	dummy := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("Content-Type", "text/html")
+		writer.Write([]byte(`<content><style>/* teaser */ .title { color: red } h1.title { font-size: 2em } @media (max-width: 600px) { h1 { font-size: 1em } }</style><h1 class="title" style="margin: 0">Hi</h1><script>track()</script><a href="#">More</a></content>`))
+	}))
+	defer dummy.Close()
+
+	const expected = `<html><head></head><body style="margin: 0"><><content><h1 class="title" style="color: red; font-size: 2em; margin: 0">Hi</h1><a href="#" style="color: blue; text-decoration: none">More</a></content></></body></html>`
+
+	templater := New(WithEmailMode())
+	actual, err := templater.Parse(strings.NewReader(fmt.Sprintf(
+		`<html><head><style>body, p:hover { margin: 0 }</style><link rel="stylesheet" href="%s"></head><body><fragment src="%s"></fragment></body></html>`,
+		stylesheet.URL, dummy.URL,
+	)))
+	assert.NoError(t, err)
+	assert.Equal(t, expected, actual)
+}
+
+func TestTemplater_Parse_EmailModeStylesheets(t *testing.T) {
+	dummy := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		switch request.URL.Path {
+		case "/teaser":
+			writer.Header().Set("Content-Type", "text/html")
+			writer.Write([]byte(`<content><link rel="stylesheet" href="teaser.css"><h1>Hi</h1></content>`))
+		case "/teaser.css":
+			writer.Header().Set("Content-Type", "text/css")
+			writer.Write([]byte(`h1 { color: red }`))
+		case "/page.css":
+			writer.Header().Set("Content-Type", "text/css")
+			writer.Write([]byte(`body { margin: 0 }`))
+		case "/large.css":
+			writer.Header().Set("Content-Type", "text/css")
+			writer.Write([]byte(`p { color: blue }` + strings.Repeat(" ", 1024)))
+		}
+	}))
+	defer dummy.Close()
+
+	const expected = `<html><head><base href="%[1]s/"/></head><body style="margin: 0"><><content><h1 style="color: red">Hi</h1></content></><p>Foo</p></body></html>`
+
+	templater := New(WithEmailMode(), WithMaxResponseBytes(512))
+	actual, err := templater.Parse(strings.NewReader(fmt.Sprintf(
+		`<html><head><base href="%[1]s/"><link rel="stylesheet" href="page.css"><link rel="stylesheet" href="large.css"></head><body><fragment src="%[1]s/teaser"></fragment><p>Foo</p></body></html>`,
+		dummy.URL,
+	)))
+	assert.NoError(t, err)
+	assert.Equal(t, fmt.Sprintf(expected, dummy.URL), actual)
+}
+
+func TestParseStylesheet(t *testing.T) {
+	rules := parseStylesheet(`#main .a.b, div { color: red } p#intro.lead { margin: 0; padding : 1px; } @font-face { font-family: x }`)
+
+	if assert.Len(t, rules, 2) {
+		assert.Len(t, rules[0].selectors, 1)
+		assert.Equal(t, "div", rules[0].selectors[0].tag)
+		assert.Equal(t, cssSelector{tag: "p", id: "intro", classes: []string{"lead"}, specificity: 111}, rules[1].selectors[0])
+		assert.Equal(t, []cssDeclaration{{property: "margin", value: "0"}, {property: "padding", value: "1px"}}, rules[1].declaration)
+	}
+}
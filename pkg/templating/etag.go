@@ -0,0 +1,71 @@
+package templating
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+
+	"golang.org/x/net/html"
+)
+
+var (
+	ErrorNotModified = errors.New("fragment not modified")
+)
+
+// ParseWithETags parses and composes the document like Parse, revalidating fragments against the
+// ETags of a prior composition, keyed by the src of the fragment. Fragments responding 304 Not Modified
+// keep their inline content, which is expected to be the content composed before, e.g. a region
+// reopened by WithForcedRecomposition. The ETags of this composition are returned for the next one.
+func (t *Templater) ParseWithETags(reader io.Reader, etags map[string]string) (string, map[string]string, error) {
+	return t.ParseWithETagsContext(context.Background(), reader, etags)
+}
+
+// ParseWithETagsContext composes the document like ParseWithETags, abandoning pending fragment
+// requests once the context is done.
+func (t *Templater) ParseWithETagsContext(ctx context.Context, reader io.Reader, etags map[string]string) (string, map[string]string, error) {
+	c := t.newComposition(ctx)
+	c.etags = etags
+	result, err := t.parseDocument(c, reader)
+	return result, c.validators, err
+}
+
+// condition makes the request conditional on the ETag of the prior composition of the fragment.
+func (c *composition) condition(node html.Node, header http.Header) {
+	if etag := c.etags[attribute(node, sourceAttribute)]; etag != "" {
+		header.Set("If-None-Match", etag)
+	}
+}
+
+// validate records the ETag of the fragment for the next composition, keeping the prior ETag of
+// fragments that were not modified.
+func (c *composition) validate(fragment html.Node, header http.Header) {
+	source := attribute(fragment, sourceAttribute)
+	etag := header.Get("ETag")
+	if header == nil {
+		etag = c.etags[source]
+	}
+	if source == "" || etag == "" {
+		return
+	}
+
+	if c.validators == nil {
+		c.validators = make(map[string]string)
+	}
+	c.validators[source] = etag
+}
+
+// retain replaces the fragment element by its inline content, as its prior composition is still fresh.
+func (t *Templater) retain(c *composition, element *html.Node) {
+	c.settle(*element, http.Header{})
+	c.validate(*element, nil)
+
+	fragment := &html.Node{Type: html.ElementNode}
+	for _, child := range detachChildren(element) {
+		fragment.AppendChild(child)
+	}
+	if t.composedMarker {
+		markComposed(fragment, attribute(*element, sourceAttribute))
+	}
+	t.insert(element, fragment)
+}
@@ -0,0 +1,57 @@
+package templating
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTemplater_ParseWithETags(t *testing.T) {
+	var (
+		version     atomic.Value
+		conditional int32
+	)
+	version.Store(`"v1"`)
+	dummy := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("Content-Type", "text/html")
+		etag := version.Load().(string)
+		if match := request.Header.Get("If-None-Match"); match != "" {
+			atomic.AddInt32(&conditional, 1)
+			if match == etag {
+				writer.WriteHeader(http.StatusNotModified)
+				return
+			}
+		}
+		writer.Header().Set("ETag", etag)
+		fmt.Fprintf(writer, "<content>fresh %s</content>", strings.Trim(etag, `"`))
+	}))
+	defer dummy.Close()
+
+	source := dummy.URL + "/teaser"
+	document := fmt.Sprintf(`<html><body><fragment src="%s"><content>composed before</content></fragment></body></html>`, source)
+
+	templater := New()
+	actual, etags, err := templater.ParseWithETags(strings.NewReader(document), nil)
+	assert.NoError(t, err)
+	assert.Equal(t, `<html><head></head><body><><content>fresh v1</content></></body></html>`, actual)
+	assert.Equal(t, map[string]string{source: `"v1"`}, etags)
+	assert.Equal(t, int32(0), atomic.LoadInt32(&conditional))
+
+	actual, etags, err = templater.ParseWithETags(strings.NewReader(document), etags)
+	assert.NoError(t, err)
+	assert.Equal(t, `<html><head></head><body><><content>composed before</content></></body></html>`, actual)
+	assert.Equal(t, map[string]string{source: `"v1"`}, etags)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&conditional))
+
+	version.Store(`"v2"`)
+	actual, etags, err = templater.ParseWithETags(strings.NewReader(document), etags)
+	assert.NoError(t, err)
+	assert.Equal(t, `<html><head></head><body><><content>fresh v2</content></></body></html>`, actual)
+	assert.Equal(t, map[string]string{source: `"v2"`}, etags)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&conditional))
+}
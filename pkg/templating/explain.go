@@ -0,0 +1,89 @@
+package templating
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html"
+)
+
+// Plan describes the fragments a composition would resolve, without fetching any of them.
+type Plan struct {
+	Fragments []PlannedFragment
+}
+
+type PlannedFragment struct {
+	ID          string
+	Source      string
+	Upstream    string
+	Group       string
+	DependsOn   string
+	As          string
+	Criticality string
+	// Timeout is the time the fragment may take at most, zero if it is unbounded.
+	Timeout time.Duration
+	// Location is the path of elements the fragment is spliced into, e.g. "html > body > div".
+	Location string
+	// Fallback is the inline content rendered when the fragment fails.
+	Fallback   string
+	Attributes map[string]string
+}
+
+func (t *Templater) Explain(reader io.Reader) (Plan, error) {
+	root, err := html.Parse(reader)
+	if err != nil {
+		return Plan{}, ErrorNoValidInput
+	}
+	adoptMarkedTemplates(root, t.tagName())
+
+	var plan Plan
+	visit(root, func(node *html.Node) {
+		if node.Type == html.ElementNode && node.Data == t.tagName() {
+			plan.Fragments = append(plan.Fragments, t.explain(*node))
+		}
+	})
+	return plan, nil
+}
+
+func (t *Templater) explain(node html.Node) PlannedFragment {
+	fragment := PlannedFragment{
+		ID:          attribute(node, idAttribute),
+		Source:      attribute(node, sourceAttribute),
+		Upstream:    attribute(node, upstreamAttribute),
+		Group:       attribute(node, groupAttribute),
+		DependsOn:   attribute(node, dependsOnAttribute),
+		As:          attribute(node, asAttribute),
+		Criticality: attribute(node, criticalityAttribute),
+		Attributes:  make(map[string]string, len(node.Attr)),
+	}
+
+	fragment.Timeout, _ = t.timeoutFor(node)
+	if total, err := totalTimeoutFor(node); err == nil && total > 0 {
+		fragment.Timeout = total
+	}
+	if budget, ok := t.groups[fragment.Group]; ok && (fragment.Timeout == 0 || budget < fragment.Timeout) {
+		fragment.Timeout = budget
+	}
+
+	for _, value := range node.Attr {
+		fragment.Attributes[value.Key] = value.Val
+	}
+
+	var location []string
+	for parent := node.Parent; parent != nil; parent = parent.Parent {
+		if parent.Type == html.ElementNode {
+			location = append([]string{parent.Data}, location...)
+		}
+	}
+	fragment.Location = strings.Join(location, " > ")
+
+	var fallback bytes.Buffer
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		html.Render(&fallback, child)
+	}
+	fragment.Fallback = fallback.String()
+
+	return fragment
+}
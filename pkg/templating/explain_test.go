@@ -0,0 +1,55 @@
+package templating
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTemplater_Explain(t *testing.T) {
+	templater := New(WithTimeout(time.Second), WithTimeoutGroup("sidebar", 300*time.Millisecond))
+
+	plan, err := templater.Explain(strings.NewReader(`<html><body>` +
+		`<fragment id="nav" src="https://nav.example.com" user-agent="bot">Navigation</fragment>` +
+		`<div><fragment group="sidebar" upstream="teaser" as="sse"><b>Teaser</b></fragment></div>` +
+		`<fragment criticality="low" src="https://low.example.com"></fragment>` +
+		`<fragment depends-on="nav" when-header="X-Variant: b" src="https://b.example.com"></fragment>` +
+		`</body></html>`))
+	assert.NoError(t, err)
+
+	assert.Equal(t, Plan{Fragments: []PlannedFragment{
+		{
+			ID:         "nav",
+			Source:     "https://nav.example.com",
+			Timeout:    time.Second,
+			Location:   "html > body",
+			Fallback:   "Navigation",
+			Attributes: map[string]string{"id": "nav", "src": "https://nav.example.com", "user-agent": "bot"},
+		},
+		{
+			Upstream:   "teaser",
+			Group:      "sidebar",
+			As:         "sse",
+			Timeout:    300 * time.Millisecond,
+			Location:   "html > body > div",
+			Fallback:   "<b>Teaser</b>",
+			Attributes: map[string]string{"group": "sidebar", "upstream": "teaser", "as": "sse"},
+		},
+		{
+			Source:      "https://low.example.com",
+			Criticality: "low",
+			Timeout:     500 * time.Millisecond,
+			Location:    "html > body",
+			Attributes:  map[string]string{"criticality": "low", "src": "https://low.example.com"},
+		},
+		{
+			Source:     "https://b.example.com",
+			DependsOn:  "nav",
+			Timeout:    time.Second,
+			Location:   "html > body",
+			Attributes: map[string]string{"depends-on": "nav", "when-header": "X-Variant: b", "src": "https://b.example.com"},
+		},
+	}}, plan)
+}
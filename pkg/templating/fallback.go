@@ -0,0 +1,54 @@
+package templating
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+const (
+	fragmentFailedAttribute = "data-fragment-failed"
+)
+
+// FallbackFunc builds the fallback of a fragment that failed to resolve with the error.
+// Returning an error renders the default fallback instead.
+type FallbackFunc func(fragment *html.Node, err error) (*html.Node, error)
+
+// fallback builds the content rendered in place of a fragment that failed to resolve, wrapped
+// in the fallback wrapper if configured.
+func (t *Templater) fallback(element *html.Node, err error) *html.Node {
+	result := t.fallbackContent(element, err)
+	if t.fallbackWrapper != "" {
+		result.Data = t.fallbackWrapper
+		result.DataAtom = atom.Lookup([]byte(t.fallbackWrapper))
+		result.Attr = append(append([]html.Attribute(nil), element.Attr...), html.Attribute{Key: fragmentFailedAttribute})
+	}
+	return result
+}
+
+// fallbackContent builds the content rendered in place of a fragment that failed to resolve.
+func (t *Templater) fallbackContent(element *html.Node, err error) *html.Node {
+	if t.fallbackFunc != nil {
+		if node, err := t.fallbackFunc(element, err); err == nil && node != nil {
+			result := &html.Node{Type: html.ElementNode}
+			result.AppendChild(node)
+			return result
+		}
+	}
+
+	var statusError StatusError
+	if t.notFound != "" && errors.As(err, &statusError) && statusError.StatusCode == http.StatusNotFound {
+		if placeholder, err := parseContent(strings.NewReader(t.notFound)); err == nil {
+			return placeholder
+		}
+	}
+
+	return &html.Node{
+		Type:       html.ElementNode,
+		FirstChild: element.FirstChild,
+		LastChild:  element.LastChild,
+	}
+}
@@ -0,0 +1,210 @@
+package templating
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+func TestTemplater_Parse_NotFoundPlaceholder(t *testing.T) {
+	dummy := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("Content-Type", "text/html")
+		switch request.URL.Path {
+		case "/missing":
+			writer.WriteHeader(http.StatusNotFound)
+		default:
+			writer.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer dummy.Close()
+
+	document := fmt.Sprintf(`<html><body><fragment src="%[1]s/missing">Temporarily unavailable</fragment><fragment src="%[1]s/broken">Temporarily unavailable</fragment></body></html>`, dummy.URL)
+
+	t.Run("should render the not found placeholder for a 404 only", func(t *testing.T) {
+		const expected = `<html><head></head><body><><p class="soon">Coming soon</p></><>Temporarily unavailable</></body></html>`
+
+		templater := New(WithNotFoundPlaceholder(`<p class="soon">Coming soon</p>`))
+		actual, err := templater.Parse(strings.NewReader(document))
+		assert.NoError(t, err)
+		assert.Equal(t, expected, actual)
+	})
+
+	t.Run("should render the inline fallback without a placeholder", func(t *testing.T) {
+		const expected = `<html><head></head><body><>Temporarily unavailable</><>Temporarily unavailable</></body></html>`
+
+		templater := New()
+		actual, err := templater.Parse(strings.NewReader(document))
+		assert.NoError(t, err)
+		assert.Equal(t, expected, actual)
+	})
+}
+
+func TestTemplater_Parse_FallbackFunc(t *testing.T) {
+	dummy := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("Content-Type", "text/html")
+		switch request.URL.Path {
+		case "/missing":
+			writer.WriteHeader(http.StatusNotFound)
+		default:
+			writer.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer dummy.Close()
+
+	var fragments []string
+	templater := New(WithFallbackFunc(func(fragment *html.Node, err error) (*html.Node, error) {
+		fragments = append(fragments, attribute(*fragment, "id"))
+
+		var statusError StatusError
+		if !errors.As(err, &statusError) || statusError.StatusCode != http.StatusInternalServerError {
+			return nil, err
+		}
+
+		message := &html.Node{Type: html.ElementNode, DataAtom: atom.P, Data: "p"}
+		message.AppendChild(&html.Node{Type: html.TextNode, Data: "Please try again later"})
+		return message, nil
+	}))
+
+	const expected = `<html><head></head><body><>Not found</><><p>Please try again later</p></></body></html>`
+
+	actual, err := templater.Parse(strings.NewReader(fmt.Sprintf(`<html><body><fragment id="a" src="%[1]s/missing">Not found</fragment><fragment id="b" src="%[1]s/broken">Broken</fragment></body></html>`, dummy.URL)))
+	assert.NoError(t, err)
+	assert.Equal(t, expected, actual)
+	assert.ElementsMatch(t, []string{"a", "b"}, fragments)
+}
+
+func TestTemplater_Parse_FallbackWrapper(t *testing.T) {
+	dummy := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("Content-Type", "text/html")
+		writer.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer dummy.Close()
+
+	document := fmt.Sprintf(`<html><body><fragment id="cart" src="%s/broken">Temporarily <b>unavailable</b></fragment></body></html>`, dummy.URL)
+
+	testCases := map[string]struct {
+		options  []Option
+		expected string
+	}{
+		"wrapped": {
+			options:  []Option{WithFallbackWrapper("div")},
+			expected: fmt.Sprintf(`<html><head></head><body><div id="cart" src="%s/broken" data-fragment-failed="">Temporarily <b>unavailable</b></div></body></html>`, dummy.URL),
+		},
+		"unwrapped by default": {
+			expected: `<html><head></head><body><>Temporarily <b>unavailable</b></></body></html>`,
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			templater := New(tc.options...)
+			actual, err := templater.Parse(strings.NewReader(document))
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+}
+
+func TestTemplater_Parse_UseErrorBody(t *testing.T) {
+	dummy := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("Content-Type", "text/html")
+		writer.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(writer, `<content><p class="missing">Page not found</p></content>`)
+	}))
+	defer dummy.Close()
+
+	tests := []struct {
+		name     string
+		flag     string
+		expected string
+	}{
+		{
+			name:     "should splice the error body with the flag set",
+			flag:     ` use-error-body="true"`,
+			expected: `<html><head></head><body><><content><p class="missing">Page not found</p></content></></body></html>`,
+		},
+		{
+			name:     "should render the inline fallback without the flag",
+			expected: `<html><head></head><body><>Temporarily unavailable</></body></html>`,
+		},
+		{
+			name:     "should render the inline fallback with the flag disabled",
+			flag:     ` use-error-body="false"`,
+			expected: `<html><head></head><body><>Temporarily unavailable</></body></html>`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			document := fmt.Sprintf(`<html><body><fragment src="%s/missing"%s>Temporarily unavailable</fragment></body></html>`, dummy.URL, tt.flag)
+
+			templater := New()
+			actual, err := templater.Parse(strings.NewReader(document))
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, actual)
+		})
+	}
+}
+
+func TestTemplater_Parse_AllFailedError(t *testing.T) {
+	failing := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("Content-Type", "text/html")
+		writer.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer failing.Close()
+
+	working := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("Content-Type", "text/html")
+		writer.Write([]byte("<content>hello</content>"))
+	}))
+	defer working.Close()
+
+	testCases := map[string]struct {
+		options  []Option
+		input    string
+		expected string
+		err      error
+	}{
+		"every fragment failed": {
+			options:  []Option{WithAllFailedError()},
+			input:    fmt.Sprintf(`<html><body><fragment src="%[1]s">Foo</fragment><div><fragment src="%[1]s">Bar</fragment></div></body></html>`, failing.URL),
+			expected: "<html><head></head><body><>Foo</><div><>Bar</></div></body></html>",
+			err:      ErrorAllFragmentsFailed,
+		},
+		"one fragment resolved": {
+			options:  []Option{WithAllFailedError()},
+			input:    fmt.Sprintf(`<html><body><fragment src="%s">Foo</fragment><div><fragment src="%s">Bar</fragment></div></body></html>`, failing.URL, working.URL),
+			expected: "<html><head></head><body><>Foo</><div><><content>hello</content></></div></body></html>",
+		},
+		"without fragments": {
+			options:  []Option{WithAllFailedError()},
+			input:    `<html><body><div>Foo</div></body></html>`,
+			expected: "<html><head></head><body><div>Foo</div></body></html>",
+		},
+		"disabled by default": {
+			input:    fmt.Sprintf(`<html><body><fragment src="%s">Foo</fragment></body></html>`, failing.URL),
+			expected: "<html><head></head><body><>Foo</></body></html>",
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			templater := New(tc.options...)
+			actual, err := templater.Parse(strings.NewReader(tc.input))
+			if tc.err != nil {
+				assert.ErrorIs(t, err, tc.err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+}
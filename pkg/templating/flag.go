@@ -0,0 +1,28 @@
+package templating
+
+import (
+	"context"
+	"errors"
+
+	"golang.org/x/net/html"
+)
+
+const (
+	flagAttribute = "flag"
+)
+
+var (
+	ErrorFlagDisabled = errors.New("feature flag of the fragment is disabled")
+)
+
+// FlagEvaluator reports whether the feature flag is enabled for the render.
+type FlagEvaluator func(flag string, ctx context.Context) bool
+
+// gated reports whether the fragment is gated behind a feature flag that is disabled.
+func (t *Templater) gated(c *composition, node html.Node) bool {
+	flag := attribute(node, flagAttribute)
+	if flag == "" || t.flags == nil {
+		return false
+	}
+	return !t.flags(flag, c.ctx)
+}
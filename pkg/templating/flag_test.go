@@ -0,0 +1,40 @@
+package templating
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTemplater_Parse_FlagEvaluator(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		requests []string
+	)
+	dummy := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("Content-Type", "text/html")
+		mu.Lock()
+		requests = append(requests, request.URL.Path)
+		mu.Unlock()
+		writer.Write([]byte("<content>Foo</content>"))
+	}))
+	defer dummy.Close()
+
+	flags := map[string]bool{"new-checkout": true, "new-header": false}
+	templater := New(WithDegradedMarker(), WithFlagEvaluator(func(flag string, ctx context.Context) bool {
+		return flags[flag]
+	}))
+
+	const expected = `<html><head></head><body><><content>Foo</content></><>Old header</><><content>Foo</content></></body></html>`
+
+	actual, err := templater.Parse(strings.NewReader(fmt.Sprintf(`<html><body><fragment flag="new-checkout" src="%[1]s/checkout"></fragment><fragment id="header" flag="new-header" src="%[1]s/header">Old header</fragment><fragment src="%[1]s/footer"></fragment></body></html>`, dummy.URL)))
+	assert.NoError(t, err)
+	assert.Equal(t, expected, actual)
+	assert.ElementsMatch(t, []string{"/checkout", "/footer"}, requests)
+}
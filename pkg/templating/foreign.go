@@ -0,0 +1,60 @@
+package templating
+
+import (
+	"errors"
+	"fmt"
+
+	"golang.org/x/net/html"
+)
+
+type ForeignFragmentPolicy int
+
+const (
+	// ForeignFragmentIgnore passes foreign fragment tags through unresolved.
+	ForeignFragmentIgnore ForeignFragmentPolicy = iota
+	// ForeignFragmentWarn passes foreign fragment tags through and reports them to the observer.
+	ForeignFragmentWarn
+	// ForeignFragmentResolve resolves foreign fragment tags like fragments of this templater.
+	ForeignFragmentResolve
+	// ForeignFragmentError renders the fallback of a fragment containing foreign fragment tags.
+	ForeignFragmentError
+)
+
+var (
+	ErrorForeignFragment = errors.New("fragment contains a foreign fragment tag")
+)
+
+// checkForeignFragments detects nested fragments within resolved content using a different tag name,
+// i.e. unknown elements with a src attribute, which would otherwise silently pass through unresolved.
+func (t *Templater) checkForeignFragments(source string, content *html.Node) error {
+	if t.foreignFragments == ForeignFragmentIgnore {
+		return nil
+	}
+
+	var err error
+	visit(content, func(node *html.Node) {
+		if !isForeignFragment(node, t.tagName()) || err != nil {
+			return
+		}
+
+		foreign := fmt.Errorf("%w: <%s>", ErrorForeignFragment, node.Data)
+		switch t.foreignFragments {
+		case ForeignFragmentWarn:
+			t.observe(Event{Kind: EventForeignFragment, Source: source, Err: foreign})
+		case ForeignFragmentResolve:
+			node.Data = t.tagName()
+		case ForeignFragmentError:
+			err = foreign
+		}
+	})
+	return err
+}
+
+func isForeignFragment(node *html.Node, tag string) bool {
+	if node.Type != html.ElementNode || node.DataAtom != 0 || node.Data == tag {
+		return false
+	}
+
+	_, ok := lookupAttribute(*node, sourceAttribute)
+	return ok
+}
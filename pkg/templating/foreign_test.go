@@ -0,0 +1,69 @@
+package templating
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTemplater_Parse_ForeignFragmentPolicy(t *testing.T) {
+	nested := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("Content-Type", "text/html")
+		writer.Write([]byte("<content>Nested</content>"))
+	}))
+	defer nested.Close()
+
+	dummy := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("Content-Type", "text/html")
+		writer.Write([]byte(fmt.Sprintf(`<content><p>Outer</p><include src="%s">Included</include><custom-element>Custom</custom-element></content>`, nested.URL)))
+	}))
+	defer dummy.Close()
+
+	tt := []struct {
+		policy   ForeignFragmentPolicy
+		expected string
+		events   int
+	}{
+		{
+			policy:   ForeignFragmentIgnore,
+			expected: fmt.Sprintf(`<><content><p>Outer</p><include src="%s">Included</include><custom-element>Custom</custom-element></content></>`, nested.URL),
+		},
+		{
+			policy:   ForeignFragmentWarn,
+			expected: fmt.Sprintf(`<><content><p>Outer</p><include src="%s">Included</include><custom-element>Custom</custom-element></content></>`, nested.URL),
+			events:   1,
+		},
+		{
+			policy:   ForeignFragmentResolve,
+			expected: `<><content><p>Outer</p><><content>Nested</content></><custom-element>Custom</custom-element></content></>`,
+		},
+		{
+			policy:   ForeignFragmentError,
+			expected: `<>Fallback</>`,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run("", func(t *testing.T) {
+			var events []Event
+			templater := New(WithForeignFragmentPolicy(tc.policy), WithObserver(ObserverFunc(func(event Event) {
+				events = append(events, event)
+			})))
+
+			actual, err := templater.Parse(strings.NewReader(fmt.Sprintf(`<html><body><fragment src="%s">Fallback</fragment></body></html>`, dummy.URL)))
+			assert.NoError(t, err)
+			assert.Equal(t, fmt.Sprintf("<html><head></head><body>%s</body></html>", tc.expected), actual)
+
+			if assert.Len(t, events, tc.events) && tc.events > 0 {
+				assert.Equal(t, EventForeignFragment, events[0].Kind)
+				assert.Equal(t, dummy.URL, events[0].Source)
+				assert.True(t, errors.Is(events[0].Err, ErrorForeignFragment))
+			}
+		})
+	}
+}
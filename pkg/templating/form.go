@@ -0,0 +1,41 @@
+package templating
+
+import (
+	"net/url"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// addReturnParams appends the configured return url parameter to the action of the forms of a
+// fragment, so submitting them leads back to the composed page. Actions already carrying the
+// parameter are kept.
+func (t *Templater) addReturnParams(content *html.Node) {
+	if t.returnParam == "" {
+		return
+	}
+
+	visit(content, func(node *html.Node) {
+		if node.Type != html.ElementNode || node.DataAtom != atom.Form {
+			return
+		}
+
+		action, ok := lookupAttribute(*node, "action")
+		if !ok {
+			return
+		}
+		location, err := url.Parse(action)
+		if err != nil {
+			return
+		}
+
+		if _, ok := location.Query()[t.returnParam]; ok {
+			return
+		}
+		if location.RawQuery != "" {
+			location.RawQuery += "&"
+		}
+		location.RawQuery += url.QueryEscape(t.returnParam) + "=" + url.QueryEscape(t.returnURL)
+		setAttribute(node, "action", location.String())
+	})
+}
@@ -0,0 +1,99 @@
+package templating
+
+import (
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// ParseRequest parses and composes the document like Parse on behalf of the incoming request,
+// forwarding its headers allowed by WithForwardHeaders to every fragment request. Pending fragment
+// requests are abandoned once the incoming request is canceled.
+func (t *Templater) ParseRequest(r *http.Request, reader io.Reader) (string, error) {
+	return t.parseDocument(t.requestComposition(r), reader)
+}
+
+// ParseResponse composes the document like ParseRequest, also returning the headers of the composed
+// response: the Vary header listing the forwarded request headers the fragments were resolved with,
+// so caches do not serve the wrong variant, and the aggregated Cache-Control of the fragments.
+func (t *Templater) ParseResponse(r *http.Request, reader io.Reader) (string, http.Header, error) {
+	c := t.requestComposition(r)
+	result, err := t.parseDocument(c, reader)
+
+	report := c.report(t.since(c.start))
+	header := make(http.Header)
+	if len(report.Vary) > 0 {
+		header.Set("Vary", strings.Join(report.Vary, ", "))
+	}
+	if cacheControl := report.CacheControl(); cacheControl != "" {
+		header.Set("Cache-Control", cacheControl)
+	}
+	return result, header, err
+}
+
+// requestComposition returns the composition of a render on behalf of the incoming request.
+func (t *Templater) requestComposition(r *http.Request) *composition {
+	c := t.newComposition(r.Context())
+	c.forward = t.forwarded(r.Header)
+	for _, name := range t.forwardHeaders {
+		c.varyBy = append(c.varyBy, http.CanonicalHeaderKey(name))
+	}
+	return c
+}
+
+// forwarded returns the allowed headers of the incoming request.
+func (t *Templater) forwarded(header http.Header) http.Header {
+	result := make(http.Header, len(t.forwardHeaders))
+	for _, name := range t.forwardHeaders {
+		if values := header.Values(name); len(values) > 0 {
+			result[http.CanonicalHeaderKey(name)] = append([]string(nil), values...)
+		}
+	}
+	return result
+}
+
+// forwardTo sets the forwarded headers of the incoming request on the fragment request.
+func (c *composition) forwardTo(header http.Header) {
+	c.vary()
+	for name, values := range c.forward {
+		header[name] = append([]string(nil), values...)
+	}
+}
+
+// forwardKey identifies the forwarded headers, as they may vary the response of a fragment.
+func (c *composition) forwardKey() string {
+	names := make([]string, 0, len(c.forward))
+	for name := range c.forward {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var builder strings.Builder
+	for _, name := range names {
+		builder.WriteString(name)
+		builder.WriteString(": ")
+		builder.WriteString(strings.Join(c.forward[name], ", "))
+		builder.WriteString("\n")
+	}
+	return builder.String()
+}
+
+// vary records that a fragment was resolved on behalf of the incoming request, whose response
+// therefore varies by all headers allowed to be forwarded, whether the request had them or not.
+func (c *composition) vary() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.varied = c.varied || len(c.varyBy) > 0
+}
+
+// varies returns the request headers the composed response varies by.
+func (c *composition) varies() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.varied {
+		return nil
+	}
+	return append([]string(nil), c.varyBy...)
+}
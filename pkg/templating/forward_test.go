@@ -0,0 +1,119 @@
+package templating
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTemplater_ParseRequest(t *testing.T) {
+	dummy := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("Content-Type", "text/html")
+		fmt.Fprintf(writer, "<content>%s|%s|%s</content>", request.Header.Get("Authorization"), request.Header.Get("Cookie"), request.Header.Get("Accept-Language"))
+	}))
+	defer dummy.Close()
+
+	incoming := httptest.NewRequest(http.MethodGet, "/", nil)
+	incoming.Header.Set("Authorization", "Bearer token")
+	incoming.Header.Set("Cookie", "session=secret")
+	incoming.Header.Set("Accept-Language", "de-DE")
+
+	testCases := map[string]struct {
+		options  []Option
+		document string
+		expected string
+	}{
+		"allowed headers": {
+			options:  []Option{WithForwardHeaders("authorization", "Accept-Language")},
+			document: `<html><body><fragment src="%s"></fragment></body></html>`,
+			expected: `<html><head></head><body><><content>Bearer token||de-DE</content></></body></html>`,
+		},
+		"lang overrides the forwarded language": {
+			options:  []Option{WithForwardHeaders("Authorization", "Accept-Language")},
+			document: `<html><body><fragment src="%s" lang="en-US"></fragment></body></html>`,
+			expected: `<html><head></head><body><><content>Bearer token||en-US</content></></body></html>`,
+		},
+		"nothing forwarded by default": {
+			document: `<html><body><fragment src="%s"></fragment></body></html>`,
+			expected: `<html><head></head><body><><content>||</content></></body></html>`,
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			templater := New(tc.options...)
+			actual, err := templater.ParseRequest(incoming, strings.NewReader(fmt.Sprintf(tc.document, dummy.URL)))
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+}
+
+func TestTemplater_ParseResponse_Vary(t *testing.T) {
+	dummy := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("Content-Type", "text/html")
+		writer.Header().Set("Cache-Control", "max-age=60")
+		fmt.Fprintf(writer, "<content>%s</content>", request.Header.Get("Accept-Language"))
+	}))
+	defer dummy.Close()
+
+	incoming := httptest.NewRequest(http.MethodGet, "/", nil)
+	incoming.Header.Set("Accept-Language", "de-DE")
+
+	testCases := map[string]struct {
+		options  []Option
+		document string
+		expected string
+		vary     string
+	}{
+		"forwarded header": {
+			options:  []Option{WithForwardHeaders("accept-language")},
+			document: `<html><body><fragment src="%s"></fragment></body></html>`,
+			expected: `<html><head></head><body><><content>de-DE</content></></body></html>`,
+			vary:     "Accept-Language",
+		},
+		"allowed header missing from the request": {
+			options:  []Option{WithForwardHeaders("Accept-Language", "Authorization")},
+			document: `<html><body><fragment src="%s"></fragment></body></html>`,
+			expected: `<html><head></head><body><><content>de-DE</content></></body></html>`,
+			vary:     "Accept-Language, Authorization",
+		},
+		"nothing forwarded": {
+			document: `<html><body><fragment src="%s"></fragment></body></html>`,
+			expected: `<html><head></head><body><><content></content></></body></html>`,
+		},
+		"no fragment resolved": {
+			options:  []Option{WithForwardHeaders("Accept-Language")},
+			document: `<html><body><p>%s</p></body></html>`,
+			expected: `<html><head></head><body><p>` + dummy.URL + `</p></body></html>`,
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			templater := New(tc.options...)
+			actual, header, err := templater.ParseResponse(incoming, strings.NewReader(fmt.Sprintf(tc.document, dummy.URL)))
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, actual)
+			assert.Equal(t, tc.vary, header.Get("Vary"))
+		})
+	}
+
+	t.Run("should vary by fragments served from the cache", func(t *testing.T) {
+		templater := New(WithForwardHeaders("Accept-Language"), WithCache(time.Minute))
+		document := fmt.Sprintf(`<html><body><fragment src="%s"></fragment></body></html>`, dummy.URL)
+		for i := 0; i < 2; i++ {
+			_, header, err := templater.ParseResponse(incoming, strings.NewReader(document))
+			assert.NoError(t, err)
+			assert.Equal(t, "Accept-Language", header.Get("Vary"))
+			assert.Equal(t, "max-age=60", header.Get("Cache-Control"))
+		}
+	})
+}
@@ -0,0 +1,92 @@
+package templating
+
+import (
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+type HeadConflictPolicy int
+
+const (
+	// HeadConflictAppend appends the links of fragments to the head, regardless of conflicts.
+	HeadConflictAppend HeadConflictPolicy = iota
+	// HeadConflictHostWins keeps the head elements of the host over conflicting ones of fragments.
+	HeadConflictHostWins
+	// HeadConflictFragmentWins replaces head elements by conflicting ones of fragments.
+	HeadConflictFragmentWins
+	// HeadConflictPrimaryWins replaces head elements by conflicting ones of the fragment with
+	// the primary attribute only, keeping them over all other fragments.
+	HeadConflictPrimaryWins
+)
+
+const (
+	primaryAttribute = "primary"
+)
+
+// hoist moves a link or meta element of the fragment content into the head, resolving conflicts
+// with existing head elements by the configured policy.
+func (t *Templater) hoist(fragment, element *html.Node) {
+	key := headKey(element)
+	if t.headConflicts == HeadConflictAppend || key == "" {
+		t.AddHeader(fragment, element)
+		return
+	}
+
+	head, err := t.FindSection("head", fragment)
+	if err != nil {
+		return
+	}
+
+	var existing *html.Node
+	for child := head.FirstChild; child != nil; child = child.NextSibling {
+		if child.Type == html.ElementNode && headKey(child) == key {
+			existing = child
+			break
+		}
+	}
+	if existing == nil {
+		t.AddHeader(fragment, element)
+		return
+	}
+
+	switch t.headConflicts {
+	case HeadConflictHostWins:
+		return
+	case HeadConflictPrimaryWins:
+		if _, ok := lookupAttribute(*fragment, primaryAttribute); !ok {
+			return
+		}
+	}
+
+	head.InsertBefore(&html.Node{
+		FirstChild: element.FirstChild,
+		LastChild:  element.LastChild,
+		Type:       element.Type,
+		DataAtom:   element.DataAtom,
+		Data:       element.Data,
+		Attr:       element.Attr,
+	}, existing)
+	head.RemoveChild(existing)
+}
+
+// headKey identifies head elements of which a document has at most one, like a viewport meta
+// or a canonical link. It is empty for elements that may occur multiple times.
+func headKey(element *html.Node) string {
+	switch element.Data {
+	case "meta":
+		if _, ok := lookupAttribute(*element, "charset"); ok {
+			return "meta charset"
+		}
+		for _, key := range []string{"name", "property", "http-equiv"} {
+			if value := attribute(*element, key); value != "" {
+				return "meta " + key + "=" + strings.ToLower(value)
+			}
+		}
+	case "link":
+		if strings.EqualFold(attribute(*element, "rel"), "canonical") {
+			return "link canonical"
+		}
+	}
+	return ""
+}
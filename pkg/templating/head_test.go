@@ -0,0 +1,73 @@
+package templating
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTemplater_Parse_HeadConflictPolicy(t *testing.T) {
+	dummy := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("Content-Type", "text/html")
+		name := strings.TrimPrefix(request.URL.Path, "/")
+		writer.Write([]byte(fmt.Sprintf(`<content><meta name="viewport" content="%[1]s"><meta name="%[1]s" content="only">%[1]s</content>`, name)))
+	}))
+	defer dummy.Close()
+
+	tt := map[string]struct {
+		policy   HeadConflictPolicy
+		document string
+		expected string
+	}{
+		"host wins": {
+			policy:   HeadConflictHostWins,
+			document: `<html><head><meta name="viewport" content="host"></head><body><fragment src="%s/teaser"></fragment></body></html>`,
+			expected: `<html><head><meta name="viewport" content="host"/><meta name="teaser" content="only"/></head><body><><content>teaser</content></></body></html>`,
+		},
+		"fragment wins": {
+			policy:   HeadConflictFragmentWins,
+			document: `<html><head><meta name="viewport" content="host"></head><body><fragment src="%s/teaser"></fragment></body></html>`,
+			expected: `<html><head><meta name="viewport" content="teaser"/><meta name="teaser" content="only"/></head><body><><content>teaser</content></></body></html>`,
+		},
+		"primary fragment wins": {
+			policy:   HeadConflictPrimaryWins,
+			document: `<html><head><meta name="viewport" content="host"></head><body><fragment src="%[1]s/secondary"></fragment><fragment primary src="%[1]s/primary"></fragment></body></html>`,
+			expected: `<html><head><meta name="viewport" content="primary"/>`,
+		},
+		"secondary fragments lose": {
+			policy:   HeadConflictPrimaryWins,
+			document: `<html><head><meta name="viewport" content="host"></head><body><fragment src="%s/secondary"></fragment></body></html>`,
+			expected: `<html><head><meta name="viewport" content="host"/><meta name="secondary" content="only"/></head><body><><content>secondary</content></></body></html>`,
+		},
+	}
+
+	for name, tc := range tt {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			templater := New(WithHeadConflictPolicy(tc.policy))
+			actual, err := templater.Parse(strings.NewReader(fmt.Sprintf(tc.document, dummy.URL)))
+			assert.NoError(t, err)
+			assert.True(t, strings.HasPrefix(actual, tc.expected), actual)
+			assert.Equal(t, 1, strings.Count(actual, `name="viewport"`))
+		})
+	}
+}
+
+func TestTemplater_Parse_DuplicateLinks(t *testing.T) {
+	dummy := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("Content-Type", "text/html")
+		writer.Write([]byte(`<link rel="stylesheet" href="https://cdn.example.com/shared.css"><content>hello</content>`))
+	}))
+	defer dummy.Close()
+
+	const expected = `<html><head><link rel="stylesheet" href="https://cdn.example.com/shared.css"/></head><body><><content>hello</content></><div><><content>hello</content></></div></body></html>`
+
+	templater := New()
+	actual, err := templater.Parse(strings.NewReader(fmt.Sprintf(`<html><body><fragment src="%[1]s/cart"></fragment><div><fragment src="%[1]s/teaser"></fragment></div></body></html>`, dummy.URL)))
+	assert.NoError(t, err)
+	assert.Equal(t, expected, actual)
+}
@@ -0,0 +1,48 @@
+package templating
+
+import (
+	"context"
+	"net/http"
+
+	"golang.org/x/net/html"
+)
+
+// hedge fetches the fragment and, if it has not responded within the hedging delay, fires a
+// second identical request, using whichever succeeds first and cancelling the other.
+// Only idempotent requests are hedged.
+func (t *Templater) hedge(req *http.Request, node html.Node) (*fragmentResponse, error) {
+	if t.hedging <= 0 || (req.Method != http.MethodGet && req.Method != http.MethodHead) {
+		return t.fetch(req, node)
+	}
+
+	ctx, cancel := context.WithCancel(req.Context())
+	defer cancel()
+
+	type outcome struct {
+		response *fragmentResponse
+		err      error
+	}
+	outcomes := make(chan outcome, 2)
+	launch := func() {
+		go func() {
+			response, err := t.fetch(req.Clone(ctx), node)
+			outcomes <- outcome{response: response, err: err}
+		}()
+	}
+
+	launch()
+	pending, delay := 1, t.after(t.hedging)
+	for {
+		select {
+		case <-delay:
+			delay = nil
+			launch()
+			pending++
+		case result := <-outcomes:
+			pending--
+			if result.err == nil || pending == 0 {
+				return result.response, result.err
+			}
+		}
+	}
+}
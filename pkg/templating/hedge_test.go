@@ -0,0 +1,59 @@
+package templating
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTemplater_Parse_Hedging(t *testing.T) {
+	tt := map[string]struct {
+		options  []Option
+		expected string
+		requests int32
+	}{
+		"hedge wins": {
+			options:  []Option{WithTimeout(time.Second), WithHedging(20 * time.Millisecond)},
+			expected: "<html><head></head><body><><content>Hedge</content></></body></html>",
+			requests: 2,
+		},
+		"without hedging": {
+			options:  []Option{WithTimeout(100 * time.Millisecond)},
+			expected: "<html><head></head><body><>Bar</></body></html>",
+			requests: 1,
+		},
+	}
+
+	for name, tc := range tt {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			var requests int32
+			dummy := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+				writer.Header().Set("Content-Type", "text/html")
+				if atomic.AddInt32(&requests, 1) == 1 {
+					select {
+					case <-time.After(time.Second):
+					case <-request.Context().Done():
+					}
+					return
+				}
+				writer.Write([]byte("<content>Hedge</content>"))
+			}))
+			defer dummy.Close()
+
+			templater := New(tc.options...)
+			start := time.Now()
+			actual, err := templater.Parse(strings.NewReader(fmt.Sprintf(`<html><body><fragment src="%s">Bar</fragment></body></html>`, dummy.URL)))
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, actual)
+			assert.Less(t, int64(time.Since(start)), int64(500*time.Millisecond))
+			assert.Equal(t, tc.requests, atomic.LoadInt32(&requests))
+		})
+	}
+}
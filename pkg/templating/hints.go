@@ -0,0 +1,191 @@
+package templating
+
+import (
+	"net/http"
+	"net/http/httptrace"
+	"net/textproto"
+	"net/url"
+	"strings"
+	"sync"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// hintRelations lists the link relations hoisted into the head from Link headers.
+var hintRelations = []string{"preload", "modulepreload", "preconnect"}
+
+// traceEarlyHints records the Link headers of 103 Early Hints responses to the request.
+func traceEarlyHints(req *http.Request) (*http.Request, func() []string) {
+	var (
+		mu    sync.Mutex
+		hints []string
+	)
+	trace := &httptrace.ClientTrace{
+		Got1xxResponse: func(code int, header textproto.MIMEHeader) error {
+			if code == http.StatusEarlyHints {
+				mu.Lock()
+				hints = append(hints, header.Values("Link")...)
+				mu.Unlock()
+			}
+			return nil
+		},
+	}
+
+	return req.WithContext(httptrace.WithClientTrace(req.Context(), trace)), func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return hints
+	}
+}
+
+// hint records the links of the Link header values of a fragment received from the location to
+// hoist into the head, resolving relative urls against the location.
+func (c *composition) hint(location string, values []string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, link := range parseLinks(values) {
+		resolveLink(location, link)
+
+		found := false
+		for _, hint := range c.hints {
+			if linkKey(hint) == linkKey(link) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			c.hints = append(c.hints, link)
+		}
+	}
+}
+
+// resolveLink resolves a relative href of the link against the base url.
+func resolveLink(location string, link html.Node) {
+	base, err := url.Parse(location)
+	if err != nil {
+		return
+	}
+
+	for i, value := range link.Attr {
+		if value.Key != "href" {
+			continue
+		}
+
+		reference, err := url.Parse(value.Val)
+		if err != nil || reference.IsAbs() {
+			continue
+		}
+		link.Attr[i].Val = base.ResolveReference(reference).String()
+	}
+}
+
+// addHints injects a link element into the head for every recorded preload or preconnect hint,
+// skipping links the head already contains.
+func (t *Templater) addHints(c *composition, root *html.Node) {
+	if len(c.hints) == 0 {
+		return
+	}
+
+	head, err := t.FindSection("head", root)
+	if err != nil {
+		return
+	}
+
+	existing := make(map[string]bool)
+	for child := head.FirstChild; child != nil; child = child.NextSibling {
+		if child.DataAtom == atom.Link {
+			existing[linkKey(*child)] = true
+		}
+	}
+
+	for _, link := range c.hints {
+		relation := strings.ToLower(attribute(link, "rel"))
+		if !hoistable(relation) {
+			continue
+		}
+
+		key := linkKey(link)
+		if existing[key] {
+			continue
+		}
+		existing[key] = true
+
+		head.AppendChild(&html.Node{
+			Type:     html.ElementNode,
+			DataAtom: atom.Link,
+			Data:     "link",
+			Attr:     link.Attr,
+		})
+	}
+}
+
+func hoistable(relation string) bool {
+	for _, value := range strings.Fields(relation) {
+		for _, candidate := range hintRelations {
+			if value == candidate {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// parseLinks parses Link header values like `</app.css>; rel=preload; as=style` into link elements.
+func parseLinks(values []string) []html.Node {
+	var links []html.Node
+	for _, value := range values {
+		for _, entry := range splitLinks(value) {
+			entry = strings.TrimSpace(entry)
+			if !strings.HasPrefix(entry, "<") {
+				continue
+			}
+			end := strings.Index(entry, ">")
+			if end < 0 {
+				continue
+			}
+
+			link := html.Node{Attr: []html.Attribute{{Key: "rel"}, {Key: "href", Val: entry[1:end]}}}
+			for _, parameter := range strings.Split(entry[end+1:], ";") {
+				key, value, _ := strings.Cut(strings.TrimSpace(parameter), "=")
+				key = strings.ToLower(strings.TrimSpace(key))
+				if key == "" || key == "href" {
+					continue
+				}
+				value = strings.Trim(strings.TrimSpace(value), `"`)
+
+				if key == "rel" {
+					link.Attr[0].Val = value
+					continue
+				}
+				link.Attr = append(link.Attr, html.Attribute{Key: key, Val: value})
+			}
+			links = append(links, link)
+		}
+	}
+	return links
+}
+
+// splitLinks splits a Link header value at the commas separating its links, ignoring commas within urls and quotes.
+func splitLinks(value string) []string {
+	var (
+		result         []string
+		start          int
+		inURL, inQuote bool
+	)
+	for i, char := range value {
+		switch {
+		case char == '<' && !inQuote:
+			inURL = true
+		case char == '>' && !inQuote:
+			inURL = false
+		case char == '"' && !inURL:
+			inQuote = !inQuote
+		case char == ',' && !inURL && !inQuote:
+			result = append(result, value[start:i])
+			start = i + 1
+		}
+	}
+	return append(result, value[start:])
+}
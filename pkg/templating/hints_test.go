@@ -0,0 +1,84 @@
+package templating
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/html"
+)
+
+func TestTemplater_Parse_EarlyHints(t *testing.T) {
+	dummy := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("Content-Type", "text/html")
+		writer.Header().Add("Link", "</app.css>; rel=preload; as=style")
+		writer.Header().Add("Link", "<https://cdn.example.com>; rel=preconnect, </next.html>; rel=prefetch")
+		writer.WriteHeader(http.StatusEarlyHints)
+
+		writer.Header().Del("Link")
+		writer.Write([]byte("<content>Foo</content>"))
+	}))
+	defer dummy.Close()
+
+	document := fmt.Sprintf(`<html><head><link rel="preconnect" href="https://cdn.example.com"></head><body><fragment src="%[1]s"></fragment><fragment src="%[1]s"></fragment></body></html>`, dummy.URL)
+
+	t.Run("should hoist the early hints into the head", func(t *testing.T) {
+		expected := fmt.Sprintf(`<html><head><link rel="preconnect" href="https://cdn.example.com"/><link rel="preload" href="%s/app.css" as="style"/></head><body><><content>Foo</content></><><content>Foo</content></></body></html>`, dummy.URL)
+
+		templater := New(WithEarlyHints())
+		actual, err := templater.Parse(strings.NewReader(document))
+		assert.NoError(t, err)
+		assert.Equal(t, expected, actual)
+	})
+
+	t.Run("should ignore the early hints by default", func(t *testing.T) {
+		const expected = `<html><head><link rel="preconnect" href="https://cdn.example.com"/></head><body><><content>Foo</content></><><content>Foo</content></></body></html>`
+
+		templater := New()
+		actual, err := templater.Parse(strings.NewReader(document))
+		assert.NoError(t, err)
+		assert.Equal(t, expected, actual)
+	})
+}
+
+func TestTemplater_Parse_LinkHeaders(t *testing.T) {
+	dummy := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("Content-Type", "text/html")
+		writer.Header().Add("Link", "</app.css>; rel=preload; as=style")
+		writer.Header().Add("Link", "<https://cdn.example.com>; rel=preconnect, </next.html>; rel=prefetch")
+		writer.Write([]byte("<content>Foo</content>"))
+	}))
+	defer dummy.Close()
+
+	document := fmt.Sprintf(`<html><head><link rel="preconnect" href="https://cdn.example.com"></head><body><fragment src="%[1]s"></fragment><fragment src="%[1]s"></fragment></body></html>`, dummy.URL)
+
+	t.Run("should hoist the preloads of the link headers into the head", func(t *testing.T) {
+		expected := fmt.Sprintf(`<html><head><link rel="preconnect" href="https://cdn.example.com"/><link rel="preload" href="%s/app.css" as="style"/></head><body><><content>Foo</content></><><content>Foo</content></></body></html>`, dummy.URL)
+
+		templater := New(WithLinkHeaders())
+		actual, err := templater.Parse(strings.NewReader(document))
+		assert.NoError(t, err)
+		assert.Equal(t, expected, actual)
+	})
+
+	t.Run("should ignore the link headers by default", func(t *testing.T) {
+		const expected = `<html><head><link rel="preconnect" href="https://cdn.example.com"/></head><body><><content>Foo</content></><><content>Foo</content></></body></html>`
+
+		templater := New()
+		actual, err := templater.Parse(strings.NewReader(document))
+		assert.NoError(t, err)
+		assert.Equal(t, expected, actual)
+	})
+}
+
+func TestParseLinks(t *testing.T) {
+	links := parseLinks([]string{`</a.css>; rel="preload"; as=style, <https://example.com/b,c.js>; rel=modulepreload; crossorigin`})
+
+	assert.Equal(t, []html.Node{
+		{Attr: []html.Attribute{{Key: "rel", Val: "preload"}, {Key: "href", Val: "/a.css"}, {Key: "as", Val: "style"}}},
+		{Attr: []html.Attribute{{Key: "rel", Val: "modulepreload"}, {Key: "href", Val: "https://example.com/b,c.js"}, {Key: "crossorigin"}}},
+	}, links)
+}
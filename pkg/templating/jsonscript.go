@@ -0,0 +1,46 @@
+package templating
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+const (
+	asJSONScript = "json-script"
+)
+
+var (
+	ErrorInvalidJSON = errors.New("fragment responded with invalid json")
+)
+
+// embedJSON wraps the json document of the reader into a script element of type application/json.
+// Characters like < and > are escaped, so the data can not close the script element.
+func embedJSON(reader io.Reader) (*html.Node, error) {
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+	if !json.Valid(data) {
+		return nil, ErrorInvalidJSON
+	}
+
+	var escaped bytes.Buffer
+	json.HTMLEscape(&escaped, bytes.TrimSpace(data))
+
+	script := &html.Node{
+		Type:     html.ElementNode,
+		DataAtom: atom.Script,
+		Data:     "script",
+		Attr:     []html.Attribute{{Key: "type", Val: "application/json"}},
+	}
+	script.AppendChild(&html.Node{Type: html.TextNode, Data: escaped.String()})
+
+	result := &html.Node{Type: html.ElementNode}
+	result.AppendChild(script)
+	return result, nil
+}
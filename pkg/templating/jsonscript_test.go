@@ -0,0 +1,50 @@
+package templating
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTemplater_Parse_JSONScript(t *testing.T) {
+	tt := map[string]struct {
+		body     string
+		expected string
+	}{
+		"embeds the data": {
+			body:     `{"user": "duc"}`,
+			expected: `<html><head></head><body><><script type="application/json" id="state">{"user": "duc"}</script></></body></html>`,
+		},
+		"escapes closing script tags": {
+			body:     `{"bio": "</script><script>alert(1)</script>"}`,
+			expected: `<html><head></head><body><><script type="application/json" id="state">{"bio": "\u003c/script\u003e\u003cscript\u003ealert(1)\u003c/script\u003e"}</script></></body></html>`,
+		},
+		"invalid json falls back": {
+			body:     `<content>Foo</content>`,
+			expected: `<html><head></head><body><>Bar</></body></html>`,
+		},
+	}
+
+	for name, tc := range tt {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			accept := make(chan string, 1)
+			dummy := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+				accept <- request.Header.Get("Accept")
+				writer.Header().Set("Content-Type", "application/json")
+				writer.Write([]byte(tc.body))
+			}))
+			defer dummy.Close()
+
+			var templater Templater
+			actual, err := templater.Parse(strings.NewReader(fmt.Sprintf(`<html><body><fragment as="json-script" id="state" src="%s">Bar</fragment></body></html>`, dummy.URL)))
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, actual)
+			assert.Equal(t, "application/json", <-accept)
+		})
+	}
+}
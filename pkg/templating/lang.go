@@ -0,0 +1,31 @@
+package templating
+
+import (
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+const (
+	langAttribute = "lang"
+)
+
+// documentLanguage returns the lang attribute of the html element of the document, the locale of the page.
+func documentLanguage(root *html.Node) string {
+	if root.DataAtom == atom.Html {
+		return attribute(*root, langAttribute)
+	}
+	for child := root.FirstChild; child != nil; child = child.NextSibling {
+		if child.DataAtom == atom.Html {
+			return attribute(*child, langAttribute)
+		}
+	}
+	return ""
+}
+
+// languageFor returns the language requested for the fragment, its lang attribute overriding the page locale.
+func (c *composition) languageFor(node html.Node) string {
+	if value, ok := lookupAttribute(node, langAttribute); ok {
+		return value
+	}
+	return c.lang
+}
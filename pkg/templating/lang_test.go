@@ -0,0 +1,47 @@
+package templating
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTemplater_Parse_Lang(t *testing.T) {
+	dummy := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("Content-Type", "text/html")
+		fmt.Fprintf(writer, "<content>%s</content>", request.Header.Get("Accept-Language"))
+	}))
+	defer dummy.Close()
+
+	testCases := map[string]struct {
+		document string
+		expected string
+	}{
+		"fragment overrides the page locale": {
+			document: `<html lang="de-DE"><body><fragment src="%s" lang="en-US"></fragment></body></html>`,
+			expected: `<html lang="de-DE"><head></head><body><><content>en-US</content></></body></html>`,
+		},
+		"page locale": {
+			document: `<html lang="de-DE"><body><fragment src="%s"></fragment></body></html>`,
+			expected: `<html lang="de-DE"><head></head><body><><content>de-DE</content></></body></html>`,
+		},
+		"without locale": {
+			document: `<html><body><fragment src="%s"></fragment></body></html>`,
+			expected: `<html><head></head><body><><content></content></></body></html>`,
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			templater := New()
+			actual, err := templater.Parse(strings.NewReader(fmt.Sprintf(tc.document, dummy.URL)))
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+}
@@ -0,0 +1,39 @@
+package templating
+
+import (
+	"errors"
+	"io"
+)
+
+const defaultMaxResponseBytes = 10 << 20
+
+var (
+	ErrorResponseTooLarge = errors.New("fragment response exceeds the maximum size")
+)
+
+// responseLimit returns the maximum size of a fragment response body, defaulting to defaultMaxResponseBytes.
+func (t *Templater) responseLimit() int64 {
+	if t.maxResponseBytes > 0 {
+		return t.maxResponseBytes
+	}
+	return defaultMaxResponseBytes
+}
+
+// limitedReader reads up to limit bytes and fails with ErrorResponseTooLarge once the body is longer.
+type limitedReader struct {
+	reader    io.Reader
+	remaining int64
+}
+
+func newLimitedReader(reader io.Reader, limit int64) *limitedReader {
+	return &limitedReader{reader: io.LimitReader(reader, limit+1), remaining: limit}
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	n, err := l.reader.Read(p)
+	l.remaining -= int64(n)
+	if l.remaining < 0 {
+		return n + int(l.remaining), ErrorResponseTooLarge
+	}
+	return n, err
+}
@@ -0,0 +1,57 @@
+package templating
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTemplater_Parse_MaxResponseBytes(t *testing.T) {
+	content := fmt.Sprintf("<content>%s</content>", strings.Repeat("a", 64))
+	dummy := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("Content-Type", "text/html")
+		writer.Write([]byte(content))
+	}))
+	defer dummy.Close()
+
+	document := fmt.Sprintf(`<html><body><fragment src="%s">Too large</fragment></body></html>`, dummy.URL)
+
+	testCases := map[string]struct {
+		limit    int64
+		expected string
+		err      error
+	}{
+		"default": {
+			expected: fmt.Sprintf(`<html><head></head><body><>%s</></body></html>`, content),
+		},
+		"exact": {
+			limit:    int64(len(content)),
+			expected: fmt.Sprintf(`<html><head></head><body><>%s</></body></html>`, content),
+		},
+		"exceeded": {
+			limit:    int64(len(content)) - 1,
+			expected: `<html><head></head><body><>Too large</></body></html>`,
+			err:      ErrorResponseTooLarge,
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			templater := New(WithMaxResponseBytes(tc.limit))
+			actual, failures, err := templater.ParseWithResult(strings.NewReader(document))
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, actual)
+			if tc.err == nil {
+				assert.Empty(t, failures)
+				return
+			}
+			if assert.Len(t, failures, 1) {
+				assert.True(t, errors.Is(failures[0], tc.err))
+			}
+		})
+	}
+}
@@ -0,0 +1,40 @@
+package templating
+
+import (
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// hardenLinks adds rel="noopener noreferrer" to links opening a new browsing context,
+// preventing the opened page from navigating the composed page.
+func (t *Templater) hardenLinks(content *html.Node) {
+	if !t.hardenExternalLinks {
+		return
+	}
+
+	visit(content, func(node *html.Node) {
+		if node.Type != html.ElementNode || (node.DataAtom != atom.A && node.DataAtom != atom.Area) {
+			return
+		}
+		if !strings.EqualFold(attribute(*node, "target"), "_blank") {
+			return
+		}
+
+		relations := strings.Fields(attribute(*node, "rel"))
+		for _, required := range []string{"noopener", "noreferrer"} {
+			found := false
+			for _, relation := range relations {
+				if strings.EqualFold(relation, required) {
+					found = true
+					break
+				}
+			}
+			if !found {
+				relations = append(relations, required)
+			}
+		}
+		setAttribute(node, "rel", strings.Join(relations, " "))
+	})
+}
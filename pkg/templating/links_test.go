@@ -0,0 +1,61 @@
+package templating
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTemplater_Parse_HardenExternalLinks(t *testing.T) {
+	dummy := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("Content-Type", "text/html")
+		writer.Write([]byte(`<content><a target="_blank" href="https://a.example.com">A</a><a target="_blank" rel="external noopener" href="https://b.example.com">B</a><a href="https://c.example.com">C</a></content>`))
+	}))
+	defer dummy.Close()
+
+	const expected = `<html><head></head><body><><content>` +
+		`<a target="_blank" href="https://a.example.com" rel="noopener noreferrer">A</a>` +
+		`<a target="_blank" rel="external noopener noreferrer" href="https://b.example.com">B</a>` +
+		`<a href="https://c.example.com">C</a>` +
+		`</content></></body></html>`
+
+	templater := New(WithHardenExternalLinks())
+	actual, err := templater.Parse(strings.NewReader(fmt.Sprintf(`<html><body><fragment src="%s"></fragment></body></html>`, dummy.URL)))
+	assert.NoError(t, err)
+	assert.Equal(t, expected, actual)
+}
+
+func TestTemplater_Parse_FormReturn(t *testing.T) {
+	dummy := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("Content-Type", "text/html")
+		writer.Write([]byte(`<content><form action="/cart/add?sku=1"></form><form action="/login?return_to=%2Faccount"></form><form></form></content>`))
+	}))
+	defer dummy.Close()
+
+	testCases := map[string]struct {
+		options  []Option
+		expected string
+	}{
+		"return param appended": {
+			options:  []Option{WithFormReturn("return_to", "https://example.com/page")},
+			expected: `<html><head></head><body><><content><form action="/cart/add?sku=1&amp;return_to=https%3A%2F%2Fexample.com%2Fpage"></form><form action="/login?return_to=%2Faccount"></form><form></form></content></></body></html>`,
+		},
+		"disabled by default": {
+			expected: `<html><head></head><body><><content><form action="/cart/add?sku=1"></form><form action="/login?return_to=%2Faccount"></form><form></form></content></></body></html>`,
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			templater := New(tc.options...)
+			actual, err := templater.Parse(strings.NewReader(fmt.Sprintf(`<html><body><fragment src="%s"></fragment></body></html>`, dummy.URL)))
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+}
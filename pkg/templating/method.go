@@ -0,0 +1,31 @@
+package templating
+
+import (
+	"net/http"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+const (
+	methodAttribute     = "method"
+	idempotentAttribute = "idempotent"
+)
+
+// methodFor returns the http method the fragment is requested with, GET unless given by its method attribute.
+func methodFor(node html.Node) string {
+	if method := strings.ToUpper(strings.TrimSpace(attribute(node, methodAttribute))); method != "" {
+		return method
+	}
+	return http.MethodGet
+}
+
+// idempotent reports whether requesting the fragment may be repeated, which holds for GET and HEAD
+// requests and for fragments explicitly marked idempotent.
+func idempotent(node html.Node) bool {
+	switch methodFor(node) {
+	case http.MethodGet, http.MethodHead:
+		return true
+	}
+	return attribute(node, idempotentAttribute) == "true"
+}
@@ -0,0 +1,63 @@
+package templating
+
+import (
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// collectModules records the urls of the module scripts within the content of a fragment.
+func (c *composition) collectModules(content *html.Node) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	visit(content, func(node *html.Node) {
+		if node.Type != html.ElementNode || node.DataAtom != atom.Script || !strings.EqualFold(attribute(*node, "type"), "module") {
+			return
+		}
+
+		source := attribute(*node, "src")
+		if source == "" {
+			return
+		}
+		for _, value := range c.modules {
+			if value == source {
+				return
+			}
+		}
+		c.modules = append(c.modules, source)
+	})
+}
+
+// addModulePreloads injects a modulepreload link for every recorded module script into the head.
+func (t *Templater) addModulePreloads(c *composition, root *html.Node) {
+	if len(c.modules) == 0 {
+		return
+	}
+
+	head, err := t.FindSection("head", root)
+	if err != nil {
+		return
+	}
+
+	existing := make(map[string]bool)
+	for child := head.FirstChild; child != nil; child = child.NextSibling {
+		if child.DataAtom == atom.Link && strings.EqualFold(attribute(*child, "rel"), "modulepreload") {
+			existing[attribute(*child, "href")] = true
+		}
+	}
+
+	for _, module := range c.modules {
+		if existing[module] {
+			continue
+		}
+
+		head.AppendChild(&html.Node{
+			Type:     html.ElementNode,
+			DataAtom: atom.Link,
+			Data:     "link",
+			Attr:     []html.Attribute{{Key: "rel", Val: "modulepreload"}, {Key: "href", Val: module}},
+		})
+	}
+}
@@ -0,0 +1,42 @@
+package templating
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTemplater_Parse_ModulePreload(t *testing.T) {
+	dummy := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("Content-Type", "text/html")
+		writer.Write([]byte(`<content><script type="module" src="https://cdn.example.com/cart.js"></script><script src="https://cdn.example.com/legacy.js"></script><script type="module">import "./inline.js";</script></content>`))
+	}))
+	defer dummy.Close()
+
+	document := fmt.Sprintf(`<html><head></head><body><fragment src="%[1]s"></fragment><fragment src="%[1]s"></fragment></body></html>`, dummy.URL)
+
+	tt := map[string]struct {
+		options  []Option
+		expected int
+	}{
+		"enabled":  {options: []Option{WithModulePreload()}, expected: 1},
+		"disabled": {expected: 0},
+	}
+
+	for name, tc := range tt {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			templater := New(tc.options...)
+			actual, err := templater.Parse(strings.NewReader(document))
+			assert.NoError(t, err)
+
+			head := actual[:strings.Index(actual, "</head>")]
+			assert.Equal(t, tc.expected, strings.Count(head, `<link rel="modulepreload" href="https://cdn.example.com/cart.js"/>`))
+			assert.Equal(t, tc.expected, strings.Count(head, "modulepreload"))
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package templating
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// negativeCache remembers failed fragment sources for a short time, so subsequent renders
+// fall back right away instead of requesting a known-broken backend again.
+type negativeCache struct {
+	mu       sync.Mutex
+	ttl      time.Duration
+	failures map[string]negativeEntry
+}
+
+type negativeEntry struct {
+	err     error
+	expires time.Time
+}
+
+// lookup returns the cached failure of the source, if it has not expired yet.
+func (n *negativeCache) lookup(source string, now time.Time) (error, bool) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	entry, ok := n.failures[source]
+	if !ok {
+		return nil, false
+	}
+	if !now.Before(entry.expires) {
+		delete(n.failures, source)
+		return nil, false
+	}
+	return entry.err, true
+}
+
+// store caches the failure of the source, or clears it on success. Failures caused by the
+// templater or the caller rather than the backend, client-side renders and revalidations are not cached.
+func (n *negativeCache) store(source string, err error, now time.Time) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	switch {
+	case err == nil:
+		delete(n.failures, source)
+	case !ownFailure(err):
+		if n.failures == nil {
+			n.failures = make(map[string]negativeEntry)
+		}
+		n.failures[source] = negativeEntry{err: err, expires: now.Add(n.ttl)}
+	}
+}
+
+// ownFailure reports whether the request did not fail because of the backend, but was rate limited,
+// queued for too long or cancelled, or whether it did not fail at all but is rendered otherwise.
+func ownFailure(err error) bool {
+	for _, target := range []error{ErrorRateLimited, ErrorQueueWait, context.Canceled, ErrorClientRender, ErrorNotModified} {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,112 @@
+package templating
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTemplater_Parse_NegativeCache(t *testing.T) {
+	var (
+		requests int32
+		healthy  int32
+	)
+	dummy := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("Content-Type", "text/html")
+		atomic.AddInt32(&requests, 1)
+		if atomic.LoadInt32(&healthy) == 0 {
+			writer.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		writer.Write([]byte("<content>Foo</content>"))
+	}))
+	defer dummy.Close()
+
+	const (
+		fallback = "<html><head></head><body><>Bar</></body></html>"
+		content  = "<html><head></head><body><><content>Foo</content></></body></html>"
+	)
+
+	clock := newFakeClock()
+	templater := New(WithClock(clock), WithNegativeCacheTTL(time.Minute))
+	render := func() string {
+		actual, err := templater.Parse(strings.NewReader(fmt.Sprintf(`<html><body><fragment src="%s">Bar</fragment></body></html>`, dummy.URL)))
+		assert.NoError(t, err)
+		return actual
+	}
+
+	assert.Equal(t, fallback, render())
+	assert.Equal(t, int32(1), atomic.LoadInt32(&requests))
+
+	atomic.StoreInt32(&healthy, 1)
+	clock.Advance(30 * time.Second)
+	assert.Equal(t, fallback, render())
+	assert.Equal(t, int32(1), atomic.LoadInt32(&requests))
+
+	clock.Advance(30 * time.Second)
+	assert.Equal(t, content, render())
+	assert.Equal(t, int32(2), atomic.LoadInt32(&requests))
+
+	atomic.StoreInt32(&healthy, 0)
+	assert.Equal(t, fallback, render())
+	atomic.StoreInt32(&healthy, 1)
+	assert.Equal(t, fallback, render())
+	assert.Equal(t, int32(3), atomic.LoadInt32(&requests))
+}
+
+func TestTemplater_ParseContext_NegativeCache(t *testing.T) {
+	var requests int32
+	dummy := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("Content-Type", "text/html")
+		atomic.AddInt32(&requests, 1)
+		writer.Write([]byte("<content>Foo</content>"))
+	}))
+	defer dummy.Close()
+
+	templater := New(WithNegativeCacheTTL(time.Minute))
+	document := fmt.Sprintf(`<html><body><fragment src="%s">Bar</fragment></body></html>`, dummy.URL)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	actual, err := templater.ParseContext(ctx, strings.NewReader(document))
+	assert.NoError(t, err)
+	assert.Equal(t, "<html><head></head><body><>Bar</></body></html>", actual)
+
+	actual, err = templater.Parse(strings.NewReader(document))
+	assert.NoError(t, err)
+	assert.Equal(t, "<html><head></head><body><><content>Foo</content></></body></html>", actual)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&requests))
+}
+
+func TestOwnFailure(t *testing.T) {
+	assert.True(t, ownFailure(ErrorQueueWait))
+	assert.True(t, ownFailure(fmt.Errorf("get: %w", context.Canceled)))
+	assert.False(t, ownFailure(StatusError{StatusCode: http.StatusInternalServerError}))
+	assert.False(t, ownFailure(context.DeadlineExceeded))
+}
+
+func TestTemplater_Parse_NegativeCacheTimeout(t *testing.T) {
+	var requests int32
+	dummy := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("Content-Type", "text/html")
+		atomic.AddInt32(&requests, 1)
+		<-request.Context().Done()
+	}))
+	defer dummy.Close()
+
+	templater := New(WithNegativeCacheTTL(time.Minute), WithTimeout(20*time.Millisecond))
+	document := fmt.Sprintf(`<html><body><fragment src="%s">Bar</fragment></body></html>`, dummy.URL)
+	for i := 0; i < 2; i++ {
+		actual, err := templater.Parse(strings.NewReader(document))
+		assert.NoError(t, err)
+		assert.Equal(t, "<html><head></head><body><>Bar</></body></html>", actual)
+	}
+	assert.Equal(t, int32(1), atomic.LoadInt32(&requests))
+}
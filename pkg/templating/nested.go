@@ -0,0 +1,34 @@
+package templating
+
+import (
+	"net/url"
+
+	"golang.org/x/net/html"
+)
+
+// absolutizeFragments resolves relative srcs of nested fragments against the url of the fragment
+// containing them, so each level of nesting resolves relative to its immediate parent.
+func absolutizeFragments(source, tag string, content *html.Node) {
+	base, err := url.Parse(source)
+	if err != nil {
+		return
+	}
+
+	visit(content, func(node *html.Node) {
+		if node.Type != html.ElementNode || node.Data != tag {
+			return
+		}
+
+		for i, value := range node.Attr {
+			if value.Key != sourceAttribute {
+				continue
+			}
+
+			reference, err := url.Parse(value.Val)
+			if err != nil || reference.IsAbs() {
+				continue
+			}
+			node.Attr[i].Val = base.ResolveReference(reference).String()
+		}
+	})
+}
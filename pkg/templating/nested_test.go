@@ -0,0 +1,132 @@
+package templating
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTemplater_Parse_NestedRelativeSource(t *testing.T) {
+	parent := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("Content-Type", "text/html")
+		switch request.URL.Path {
+		case "/widgets/parent":
+			writer.Write([]byte(`<content><fragment src="child">Child fallback</fragment><fragment src="/root">Root fallback</fragment></content>`))
+		case "/widgets/child":
+			writer.Write([]byte(`<content>Child of parent</content>`))
+		case "/root":
+			writer.Write([]byte(`<content>Root of parent</content>`))
+		default:
+			writer.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer parent.Close()
+
+	const expected = `<html><head></head><body><><content><><content>Child of parent</content></><><content>Root of parent</content></></content></></body></html>`
+
+	templater := New()
+	actual, err := templater.Parse(strings.NewReader(fmt.Sprintf(`<html><body><fragment src="%s/widgets/parent"></fragment></body></html>`, parent.URL)))
+	assert.NoError(t, err)
+	assert.Equal(t, expected, actual)
+}
+
+func TestTemplater_Parse_FragmentTag(t *testing.T) {
+	dummy := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("Content-Type", "text/html")
+		switch request.URL.Path {
+		case "/parent":
+			writer.Write([]byte(`<content><include src="/child">Child fallback</include></content>`))
+		case "/child":
+			writer.Write([]byte(`<content>hello</content>`))
+		}
+	}))
+	defer dummy.Close()
+
+	const expected = `<html><head></head><body><><content><><content>hello</content></></content></><fragment>Foo</fragment></body></html>`
+
+	templater := New(WithFragmentTag("include"))
+	actual, err := templater.Parse(strings.NewReader(fmt.Sprintf(`<html><body><include src="%s/parent"></include><fragment>Foo</fragment></body></html>`, dummy.URL)))
+	assert.NoError(t, err)
+	assert.Equal(t, expected, actual)
+}
+
+func TestTemplater_Parse_DeepNesting(t *testing.T) {
+	const levels = 200
+
+	dummy := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("Content-Type", "text/html")
+		var level int
+		fmt.Sscanf(request.URL.Path, "/%d", &level)
+		if level == levels {
+			writer.Write([]byte(`<content>bottom</content>`))
+			return
+		}
+		fmt.Fprintf(writer, `<content>%d<fragment src="/%d">missing</fragment></content>`, level, level+1)
+	}))
+	defer dummy.Close()
+
+	testCases := map[string]struct {
+		depth    int
+		resolved int
+	}{
+		"all levels": {
+			depth:    levels + 1,
+			resolved: levels + 1,
+		},
+		"depth limit": {
+			depth:    levels / 2,
+			resolved: levels / 2,
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			var expected strings.Builder
+			expected.WriteString("<html><head></head><body>")
+			for level := 0; level < tc.resolved; level++ {
+				if level == levels {
+					expected.WriteString("<><content>bottom</content></>")
+					continue
+				}
+				fmt.Fprintf(&expected, "<><content>%d", level)
+			}
+			if tc.resolved <= levels {
+				expected.WriteString("<>missing</>")
+			}
+			for level := 0; level < tc.resolved && level < levels; level++ {
+				expected.WriteString("</content></>")
+			}
+			expected.WriteString("</body></html>")
+
+			templater := New(WithMaxDepth(tc.depth))
+			actual, err := templater.Parse(strings.NewReader(fmt.Sprintf(`<html><body><fragment src="%s/0"></fragment></body></html>`, dummy.URL)))
+			assert.NoError(t, err)
+			assert.Equal(t, expected.String(), actual)
+		})
+	}
+}
+
+func TestTemplater_Parse_NestedLinks(t *testing.T) {
+	dummy := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("Content-Type", "text/html")
+		switch request.URL.Path {
+		case "/parent":
+			writer.Write([]byte(`<link rel="stylesheet" href="/parent.css"><content><fragment src="/child">Child fallback</fragment></content>`))
+		case "/child":
+			writer.Write([]byte(`<link rel="stylesheet" href="/child.css"><content>hello</content>`))
+		}
+	}))
+	defer dummy.Close()
+
+	const expected = `<html><head><link rel="stylesheet" href="/parent.css"/><link rel="stylesheet" href="/child.css"/></head><body><><content><><content>hello</content></></content></></body></html>`
+
+	templater := New()
+	actual, err := templater.Parse(strings.NewReader(fmt.Sprintf(`<html><body><fragment src="%s/parent"></fragment></body></html>`, dummy.URL)))
+	assert.NoError(t, err)
+	assert.Equal(t, expected, actual)
+}
@@ -0,0 +1,37 @@
+package templating
+
+import "time"
+
+type EventKind int
+
+const (
+	// EventSLOViolation is emitted when a fragment resolved successfully but took longer than the configured SLO.
+	EventSLOViolation EventKind = iota
+	// EventForeignFragment is emitted when resolved content contains a fragment tag of another service.
+	EventForeignFragment
+)
+
+// Event describes something noteworthy that happened while resolving a fragment.
+type Event struct {
+	Kind     EventKind
+	Source   string
+	Duration time.Duration
+	Err      error
+}
+
+type Observer interface {
+	Observe(event Event)
+}
+
+// ObserverFunc adapts an ordinary function to the Observer interface.
+type ObserverFunc func(event Event)
+
+func (f ObserverFunc) Observe(event Event) {
+	f(event)
+}
+
+func (t *Templater) observe(event Event) {
+	if t.observer != nil {
+		t.observer.Observe(event)
+	}
+}
@@ -0,0 +1,41 @@
+package templating
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/html"
+)
+
+func TestTemplater_ParseWithNode_FragmentSLO(t *testing.T) {
+	slow := slowDummy(50*time.Millisecond, "<content>Slow</content>")
+	defer slow.Close()
+	fast := slowDummy(0, "<content>Fast</content>")
+	defer fast.Close()
+
+	var events []Event
+	templater := New(
+		WithFragmentSLO(30*time.Millisecond),
+		WithObserver(ObserverFunc(func(event Event) {
+			events = append(events, event)
+		})),
+	)
+
+	const expected = "<html><head></head><body><><content>Slow</content></><><content>Fast</content></></body></html>"
+	root, _ := html.Parse(strings.NewReader(fmt.Sprintf(`<html><body><fragment src="%s"></fragment><fragment src="%s"></fragment></body></html>`, slow.URL, fast.URL)))
+	templater.ParseWithNode(root)
+
+	var actual bytes.Buffer
+	html.Render(&actual, root)
+	assert.Equal(t, expected, actual.String())
+
+	if assert.Len(t, events, 1) {
+		assert.Equal(t, EventSLOViolation, events[0].Kind)
+		assert.Equal(t, slow.URL, events[0].Source)
+		assert.GreaterOrEqual(t, int64(events[0].Duration), int64(50*time.Millisecond))
+	}
+}
@@ -0,0 +1,628 @@
+package templating
+
+import (
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/sync/singleflight"
+	"golang.org/x/time/rate"
+)
+
+type Option func(*Templater)
+
+// WithClient sets the client requesting the fragments, e.g. to configure its transport.
+// Options adjusting the transport, like WithHostOverride, must follow it.
+func WithClient(client *http.Client) Option {
+	return func(t *Templater) {
+		t.client = client
+	}
+}
+
+// WithUserAgent sets the User-Agent header sent with every fragment request.
+// A fragment can override it with its own user-agent attribute.
+func WithUserAgent(userAgent string) Option {
+	return func(t *Templater) {
+		t.userAgent = userAgent
+	}
+}
+
+// WithAssetCDN rewrites the urls of images, scripts, styles and other assets
+// of a resolved fragment with the given mapping, e.g. to serve them from a CDN.
+func WithAssetCDN(mapping func(assetURL string) string) Option {
+	return func(t *Templater) {
+		t.assetCDN = mapping
+	}
+}
+
+// WithTimeout sets the default time a fragment may take to resolve before the fallback is rendered.
+// The timeout attribute of a fragment overrides it, e.g. timeout="500ms". Fragments with a
+// total-timeout attribute apply it to every single attempt instead. A fragment with an invalid
+// timeout attribute is not requested but falls back with ErrorInvalidTimeout, listed by ParseWithResult.
+func WithTimeout(timeout time.Duration) Option {
+	return func(t *Templater) {
+		t.timeout = timeout
+	}
+}
+
+// WithTimeoutGroup assigns a combined time budget to all fragments sharing the given group attribute.
+// Once the budget of a render is spent, the remaining members of the group fall back immediately.
+func WithTimeoutGroup(name string, budget time.Duration) Option {
+	return func(t *Templater) {
+		if t.groups == nil {
+			t.groups = make(map[string]time.Duration)
+		}
+		t.groups[name] = budget
+	}
+}
+
+// WithObserver registers an observer notified about noteworthy events during composition.
+func WithObserver(observer Observer) Option {
+	return func(t *Templater) {
+		t.observer = observer
+	}
+}
+
+// WithFragmentSLO reports fragments resolving slower than the given threshold as SLO violations
+// to the observer. Unlike a timeout, the content of a slow fragment is still used.
+func WithFragmentSLO(threshold time.Duration) Option {
+	return func(t *Templater) {
+		t.slo = threshold
+	}
+}
+
+// WithEmailMode renders email client safe documents: after composition the rules of all
+// style elements and linked stylesheets are inlined into style attributes and scripts are removed.
+func WithEmailMode() Option {
+	return func(t *Templater) {
+		t.emailMode = true
+	}
+}
+
+// WithRateLimit limits the outgoing fragment requests of all renders to rps requests per second,
+// allowing bursts of up to burst requests. A fragment whose turn would come after its deadline falls back.
+func WithRateLimit(rps int, burst int) Option {
+	return func(t *Templater) {
+		t.limiter = rate.NewLimiter(rate.Limit(rps), burst)
+	}
+}
+
+// WithTransformForPattern registers a transform for all fragments whose src matches the glob pattern,
+// e.g. "*.example.com/*". The transforms of all matching patterns run in the order of registration.
+func WithTransformForPattern(pattern string, fn TransformFunc) Option {
+	return func(t *Templater) {
+		t.transforms = append(t.transforms, patternTransform{pattern: globPattern(pattern), transform: fn})
+	}
+}
+
+// WithRequestCoalescing lets concurrent renders share a single request for identical fragments,
+// each of them receiving its own copy of the content.
+func WithRequestCoalescing() Option {
+	return func(t *Templater) {
+		t.flights = &singleflight.Group{}
+	}
+}
+
+// WithMixedContentPolicy sets how insecure http asset urls within fragments are handled
+// when the composed page is served over https.
+func WithMixedContentPolicy(policy MixedContentPolicy) Option {
+	return func(t *Templater) {
+		t.mixedContent = policy
+	}
+}
+
+// WithProtocolRelativePolicy sets how protocol-relative asset urls within fragments, e.g. //cdn/x.js,
+// are handled. It applies before the asset CDN rewriting and leaves absolute urls untouched.
+func WithProtocolRelativePolicy(policy ProtocolRelativePolicy) Option {
+	return func(t *Templater) {
+		t.protocolRelative = policy
+	}
+}
+
+// WithDegradedMarker lists the ids of all fragments that fell back in a data-degraded attribute
+// of the body, so styles and scripts can react to a partially degraded page.
+func WithDegradedMarker() Option {
+	return func(t *Templater) {
+		t.degradedMarker = true
+	}
+}
+
+// WithUpstream registers a named upstream, referenced by the upstream attribute of a fragment,
+// whose requests are balanced over the given replica urls. A failing replica is skipped for the next one.
+// An upstream without urls is ignored, so its fragments fail as unknown upstream.
+func WithUpstream(name string, urls []string, strategy Strategy) Option {
+	return func(t *Templater) {
+		if len(urls) == 0 {
+			return
+		}
+		if t.upstreams == nil {
+			t.upstreams = make(map[string]*upstream)
+		}
+		t.upstreams[name] = &upstream{urls: slices.Clone(urls), strategy: strategy}
+	}
+}
+
+// WithPreconnectOrigins injects a preconnect link into the head for every distinct origin
+// of the resolved fragments and their assets.
+func WithPreconnectOrigins() Option {
+	return func(t *Templater) {
+		t.preconnect = true
+	}
+}
+
+// WithForeignFragmentPolicy sets how nested fragments using another tag name than this
+// templater, e.g. due to configuration drift between services, are handled.
+func WithForeignFragmentPolicy(policy ForeignFragmentPolicy) Option {
+	return func(t *Templater) {
+		t.foreignFragments = policy
+	}
+}
+
+// WithCriticality sets the multiplier applied to the timeout of fragments with the given criticality attribute.
+// By default high doubles, medium keeps and low halves the timeout.
+func WithCriticality(level string, multiplier float64) Option {
+	return func(t *Templater) {
+		if t.criticality == nil {
+			t.criticality = make(map[string]float64)
+		}
+		t.criticality[level] = multiplier
+	}
+}
+
+// WithClock replaces the real clock used to measure durations and to wait, e.g. by a fake one in tests.
+// It drives the expiry of caches and circuit breakers, backoffs, rate limits and time budgets, while
+// timeouts and deadlines of fragment requests are bound to their context and keep using the real clock.
+func WithClock(clock Clock) Option {
+	return func(t *Templater) {
+		t.clock = clock
+	}
+}
+
+// WithAuditLog writes a JSON record of every render to the writer, listing the request id,
+// all fragments with their status, duration and fallback, and the total duration.
+func WithAuditLog(writer io.Writer) Option {
+	return func(t *Templater) {
+		t.audit = &auditLog{writer: writer}
+	}
+}
+
+// WithWebSocketFragments enables fragments with as="ws", resolved from the first message of a websocket.
+func WithWebSocketFragments() Option {
+	return func(t *Templater) {
+		t.webSockets = true
+	}
+}
+
+// WithHardenExternalLinks adds rel="noopener noreferrer" to links of fragments opening in a new tab.
+func WithHardenExternalLinks() Option {
+	return func(t *Templater) {
+		t.hardenExternalLinks = true
+	}
+}
+
+// WithNotFoundPlaceholder renders the given markup for fragments responding 404 Not Found,
+// instead of their inline fallback used for all other failures.
+func WithNotFoundPlaceholder(markup string) Option {
+	return func(t *Templater) {
+		t.notFound = markup
+	}
+}
+
+// WithWrapper sets the tag wrapping the content of every fragment, "none" splices the content
+// without a wrapping element. The wrapper attribute of a fragment overrides it.
+func WithWrapper(tag string) Option {
+	return func(t *Templater) {
+		t.wrapper = tag
+	}
+}
+
+// WithRetryOnEmpty requests a fragment again, up to maxRetries times, while its backend
+// responds with empty content. Retries stay within the timeout of the fragment.
+func WithRetryOnEmpty(maxRetries int) Option {
+	return func(t *Templater) {
+		t.retryOnEmpty = maxRetries
+	}
+}
+
+// WithRetries requests a fragment again, up to maxRetries times, after it failed with a 5xx status
+// or a network error. Retries stay within the timeout of the fragment, see WithBackoff for the delay between them.
+func WithRetries(maxRetries int) Option {
+	return func(t *Templater) {
+		t.retries = maxRetries
+	}
+}
+
+// WithCompositionToken sends the token returned by the function in the X-Composition-Token header
+// of every fragment request, letting backends verify the request came from the composition.
+// The function is called once per render.
+func WithCompositionToken(token func() string) Option {
+	return func(t *Templater) {
+		t.compositionToken = token
+	}
+}
+
+// WithCollapsedFallbacks merges adjacent fallbacks with identical content into a single element,
+// counting the merged fallbacks in its data-collapsed attribute.
+func WithCollapsedFallbacks() Option {
+	return func(t *Templater) {
+		t.collapseFallbacks = true
+	}
+}
+
+// WithHostPolicy overrides the default timeout and retries for all fragments served by the host,
+// given as hostname or hostname:port. Attributes of a fragment still take precedence.
+func WithHostPolicy(host string, policy Policy) Option {
+	return func(t *Templater) {
+		if t.hosts == nil {
+			t.hosts = make(map[string]Policy)
+		}
+		t.hosts[host] = policy
+	}
+}
+
+// WithHedging fires a second identical request for a fragment that has not responded within the delay,
+// using whichever response arrives first. Only idempotent requests are hedged.
+func WithHedging(delay time.Duration) Option {
+	return func(t *Templater) {
+		t.hedging = delay
+	}
+}
+
+// WithHostOverride dials the given ip:port instead of resolving the hostname of a fragment,
+// e.g. to target a canary instance. The Host header of the request is left unchanged.
+// The transport of the client is kept, but only an *http.Transport dials the overrides.
+func WithHostOverride(overrides map[string]string) Option {
+	return func(t *Templater) {
+		t.hostOverrides = make(map[string]string, len(overrides))
+		for host, address := range overrides {
+			t.hostOverrides[host] = address
+		}
+		client := *t.httpClient()
+		client.Transport = overrideTransport(client.Transport, t.hostOverrides)
+		t.client = &client
+	}
+}
+
+// WithModulePreload injects a modulepreload link into the head for every module script of the
+// resolved fragments, so the browser fetches the module graph early.
+func WithModulePreload() Option {
+	return func(t *Templater) {
+		t.modulePreload = true
+	}
+}
+
+// WithNegativeCacheTTL caches the failure of a fragment source for the given duration, so renders
+// within it fall back without requesting the source again. A success clears the failure.
+func WithNegativeCacheTTL(ttl time.Duration) Option {
+	return func(t *Templater) {
+		t.failures = &negativeCache{ttl: ttl}
+	}
+}
+
+// WithTemplateSrc evaluates the src of fragments as text/template against the variables passed
+// to ParseWithVars, e.g. src="{{if .mobile}}https://m.nav{{else}}https://nav{{end}}". Printed values
+// are escaped as path segment, or as query value in the query and host, unless they start the src.
+func WithTemplateSrc() Option {
+	return func(t *Templater) {
+		t.templateSrc = true
+	}
+}
+
+// WithAdaptiveConcurrency bounds the concurrent fragment requests of all renders by a limit that
+// grows while backends respond fast and shrinks as their latency climbs.
+func WithAdaptiveConcurrency() Option {
+	return func(t *Templater) {
+		t.adaptive = newAdaptiveLimiter()
+	}
+}
+
+// WithFallbackFunc builds the fallback of failed fragments dynamically, e.g. from a cached snapshot.
+// The returned node must not be attached to a tree. If the function returns an error,
+// the not found placeholder or the inline fallback is rendered.
+func WithFallbackFunc(fallback FallbackFunc) Option {
+	return func(t *Templater) {
+		t.fallbackFunc = fallback
+	}
+}
+
+// WithDeadlineHeader sends the time remaining for a fragment in the given request header. The
+// grpc-timeout header uses the gRPC timeout format, all other headers the number of milliseconds.
+func WithDeadlineHeader(name string) Option {
+	return func(t *Templater) {
+		t.deadlineHeader = name
+	}
+}
+
+// WithTrimLeadingWhitespace removes the whitespace before the markup of fragment responses,
+// which would otherwise be spliced as a stray text node.
+func WithTrimLeadingWhitespace() Option {
+	return func(t *Templater) {
+		t.trimLeadingWhitespace = true
+	}
+}
+
+// WithClientRenderSignal renders a deferred placeholder, resolved client-side, for fragments
+// responding with the given header value, e.g. X-Render: client for auth-gated content.
+func WithClientRenderSignal(header, value string) Option {
+	return func(t *Templater) {
+		t.clientRenderHeader = header
+		t.clientRenderValue = value
+	}
+}
+
+// WithMaxQueueWait bounds the time a fragment waits for a request slot of the concurrency limit,
+// rendering its fallback once the wait is exceeded.
+func WithMaxQueueWait(wait time.Duration) Option {
+	return func(t *Templater) {
+		t.maxQueueWait = wait
+	}
+}
+
+// WithRawResponses retains the unparsed response body of every fragment in its report,
+// e.g. to compute checksums. Event stream fragments retain the data of their first event.
+// See ParseWithReport.
+func WithRawResponses() Option {
+	return func(t *Templater) {
+		t.retainRaw = true
+	}
+}
+
+// WithComposedMarker marks the content of resolved fragments with a data-composed attribute holding
+// their source. Composing a marked document again skips the marked regions.
+// The marker holds the full url the fragment was requested from, including internal hostnames and
+// substituted variables, so marked documents should be stored for re-composition rather than served
+// to clients as is.
+func WithComposedMarker() Option {
+	return func(t *Templater) {
+		t.composedMarker = true
+	}
+}
+
+// WithForcedRecomposition resolves the regions marked by WithComposedMarker again when composing
+// a marked document, instead of skipping them.
+func WithForcedRecomposition() Option {
+	return func(t *Templater) {
+		t.forceRecompose = true
+	}
+}
+
+// WithEarlyHints hoists the preload and preconnect links of 103 Early Hints responses
+// of the fragments into the head.
+func WithEarlyHints() Option {
+	return func(t *Templater) {
+		t.earlyHints = true
+	}
+}
+
+// WithLinkHeaders hoists the preload and preconnect links of the Link response headers of the
+// fragments into the head.
+func WithLinkHeaders() Option {
+	return func(t *Templater) {
+		t.linkHeaders = true
+	}
+}
+
+// WithHeadConflictPolicy sets how links and metas of fragments conflicting with elements already
+// in the head, like a viewport meta or a canonical link, are resolved. Other than by default,
+// metas of fragments are hoisted into the head as well.
+func WithHeadConflictPolicy(policy HeadConflictPolicy) Option {
+	return func(t *Templater) {
+		t.headConflicts = policy
+	}
+}
+
+// WithFlagEvaluator gates fragments with a flag attribute behind the feature flag, evaluated per render.
+// Fragments with a disabled flag are not requested and render their inline fallback.
+func WithFlagEvaluator(evaluator FlagEvaluator) Option {
+	return func(t *Templater) {
+		t.flags = evaluator
+	}
+}
+
+// WithMaxConcurrency resolves the fragments of a document concurrently, with at most the given number
+// of requests in flight. The resolved fragments are spliced in document order once all completed.
+func WithMaxConcurrency(limit int) Option {
+	return func(t *Templater) {
+		t.maxConcurrency = limit
+	}
+}
+
+// WithParseTimeout renders the fallback of fragments whose markup takes longer than the timeout
+// to parse, e.g. because of pathologically deep nesting.
+func WithParseTimeout(timeout time.Duration) Option {
+	return func(t *Templater) {
+		t.parseTimeout = timeout
+	}
+}
+
+// WithBackoff sets the delay between retries of a fragment, e.g. ExponentialBackoff.
+// Without a backoff, retries are sent right away.
+func WithBackoff(backoff Backoff) Option {
+	return func(t *Templater) {
+		t.backoff = backoff
+	}
+}
+
+// WithAllFailedError returns ErrorAllFragmentsFailed along with the rendered document if every
+// fragment of the page fell back, e.g. to serve it with a 503.
+func WithAllFailedError() Option {
+	return func(t *Templater) {
+		t.allFailedError = true
+	}
+}
+
+// WithMaxDepth limits how deep fragments may be nested in the content of other fragments, 5 by default.
+// Fragments nested deeper render their fallback, so fragments embedding each other terminate.
+func WithMaxDepth(depth int) Option {
+	return func(t *Templater) {
+		t.maxDepth = depth
+	}
+}
+
+// WithDepthComment marks fragments that exceeded the maximum depth with an HTML comment before their fallback.
+func WithDepthComment() Option {
+	return func(t *Templater) {
+		t.depthComment = true
+	}
+}
+
+// WithOrderedQuery keeps the order of query parameters in cache keys, for backends whose responses
+// depend on it. By default the parameters are sorted, so reordered but equivalent urls share cache entries.
+func WithOrderedQuery() Option {
+	return func(t *Templater) {
+		t.orderedQuery = true
+	}
+}
+
+// WithFallbackWrapper renders the fallback of failed fragments in an element of the tag, carrying the
+// attributes of the fragment and a data-fragment-failed attribute, so failed fragments can be styled or detected.
+func WithFallbackWrapper(tag string) Option {
+	return func(t *Templater) {
+		t.fallbackWrapper = tag
+	}
+}
+
+// WithWarmupTimeout bounds the dns lookup and connect phase of Warmup, 5 seconds by default.
+func WithWarmupTimeout(timeout time.Duration) Option {
+	return func(t *Templater) {
+		t.warmupTimeout = timeout
+	}
+}
+
+// WithFragmentTag sets the tag name of fragment elements, e.g. include, instead of fragment.
+func WithFragmentTag(tag string) Option {
+	return func(t *Templater) {
+		t.fragmentTag = strings.ToLower(tag)
+	}
+}
+
+// WithRuntimeScript injects a script of the src hydrating deferred fragments client-side, once and
+// only into documents that contain deferred fragments.
+func WithRuntimeScript(src string) Option {
+	return func(t *Templater) {
+		t.runtimeScript = src
+	}
+}
+
+// WithCache caches resolved fragments for the max-age of their Cache-Control header, or the ttl if
+// the response states none. Fragments with a no-cache attribute are always requested.
+func WithCache(ttl time.Duration) Option {
+	return func(t *Templater) {
+		t.cache = &responseCache{ttl: ttl}
+	}
+}
+
+// WithForwardHeaders forwards the headers of the incoming request given to ParseRequest, e.g. Authorization
+// or Cookie, to every fragment request.
+func WithForwardHeaders(names ...string) Option {
+	return func(t *Templater) {
+		t.forwardHeaders = append(t.forwardHeaders, names...)
+	}
+}
+
+// WithCircuitBreaker falls back right away for the fragments of a host for the cooldown, once its
+// requests failed threshold consecutive times within the window. Only server errors, network errors
+// and timeouts count as failures.
+func WithCircuitBreaker(threshold int, window, cooldown time.Duration) Option {
+	return func(t *Templater) {
+		t.breaker = &circuitBreaker{threshold: threshold, window: window, cooldown: cooldown}
+	}
+}
+
+// WithConnectionBreaker opens the connection circuit of a fragment host for the cooldown, once
+// connecting to it failed threshold consecutive times within the window, e.g. on dns errors or
+// refused and reset connections. Fragments of the host fall back with ErrorConnectionCircuitOpen
+// meanwhile. Connection failures then no longer count for WithCircuitBreaker.
+func WithConnectionBreaker(threshold int, window, cooldown time.Duration) Option {
+	return func(t *Templater) {
+		t.connectionBreaker = &circuitBreaker{threshold: threshold, window: window, cooldown: cooldown, counts: connectionFailure, open: ErrorConnectionCircuitOpen}
+	}
+}
+
+// WithDebugErrorComment appends an HTML comment summarizing all failed fragments to the body,
+// e.g. <!-- composition errors: nav(500), footer(timeout) -->. Meant for debugging outside production.
+func WithDebugErrorComment() Option {
+	return func(t *Templater) {
+		t.debugErrorComment = true
+	}
+}
+
+// WithPreFetch consults the hook before requesting a fragment, using the html it provides instead.
+func WithPreFetch(preFetch PreFetchFunc) Option {
+	return func(t *Templater) {
+		t.preFetchFunc = preFetch
+	}
+}
+
+// WithFormReturn appends the return url as query parameter param to the action of forms in fragments,
+// e.g. WithFormReturn("return_to", "https://example.com/cart"), unless the action already has it.
+func WithFormReturn(param, returnURL string) Option {
+	return func(t *Templater) {
+		t.returnParam, t.returnURL = param, returnURL
+	}
+}
+
+// WithMaxResponseBytes limits the size of fragment response bodies and websocket messages, 10MB by default.
+// Larger responses fail with ErrorResponseTooLarge and render the fallback.
+func WithMaxResponseBytes(limit int64) Option {
+	return func(t *Templater) {
+		t.maxResponseBytes = limit
+	}
+}
+
+// WithInlineScriptDedup hoists inline scripts of fragments only once per document, comparing them by
+// the hash of their content. External scripts are always deduplicated by src.
+func WithInlineScriptDedup() Option {
+	return func(t *Templater) {
+		t.dedupInlineScripts = true
+	}
+}
+
+// WithContentTypes parses fragment responses of the given media types instead of text/html and
+// application/xhtml+xml, which are accepted by default or if none are given. Responses of other
+// types fail with ErrorUnexpectedContentType and render the fallback.
+func WithContentTypes(types ...string) Option {
+	return func(t *Templater) {
+		t.contentTypes = append([]string{}, types...)
+	}
+}
+
+// WithHeadPrecheck requests the head of every fragment first and renders the fallback right away
+// unless it succeeds. Backends responding 405 or 501 to HEAD requests are requested as usual.
+func WithHeadPrecheck() Option {
+	return func(t *Templater) {
+		t.headPrecheck = true
+	}
+}
+
+// WithDoctype renders composed documents with the doctype declaration, e.g. "<!DOCTYPE html>",
+// whatever doctype the input had. Without it the doctype of the input is preserved.
+func WithDoctype(doctype string) Option {
+	return func(t *Templater) {
+		t.doctype = doctype
+	}
+}
+
+// WithRedirectPolicy sets how redirects of fragment requests are handled, following at most
+// maxRedirects hops, 10 if not positive, or failing on any redirect. Failed fragments render their fallback.
+func WithRedirectPolicy(policy RedirectPolicy, maxRedirects int) Option {
+	return func(t *Templater) {
+		t.redirects = true
+		t.redirectPolicy, t.maxRedirects = policy, maxRedirects
+	}
+}
+
+// WithTracer records a span for the resolution of every fragment with the tracer, or the tracer of
+// the global OpenTelemetry tracer provider if nil. The spans carry the src, status and duration of
+// the fragment, whether it fell back and whether it was served from the cache.
+func WithTracer(tracer trace.Tracer) Option {
+	return func(t *Templater) {
+		if tracer == nil {
+			tracer = globalTracer()
+		}
+		t.tracer = tracer
+	}
+}
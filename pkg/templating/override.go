@@ -0,0 +1,54 @@
+package templating
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"time"
+)
+
+// overrideTransport returns a copy of the transport dialing the overridden address for the hosts of
+// the overrides, keeping all other settings like its TLS configuration and its own dialer.
+// Round trippers other than *http.Transport can not be redirected and are returned as is.
+func overrideTransport(base http.RoundTripper, overrides map[string]string) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	transport, ok := base.(*http.Transport)
+	if !ok {
+		return base
+	}
+
+	dial := transport.DialContext
+	if dial == nil {
+		dial = (&net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}).DialContext
+	}
+
+	transport = transport.Clone()
+	transport.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return dial(ctx, network, overrideAddress(overrides, addr))
+	}
+	return transport
+}
+
+// overrideAddress maps the host:port address to its override, matching the address first and
+// the hostname second. Overrides without port keep the port of the address.
+func overrideAddress(overrides map[string]string, addr string) string {
+	if override, ok := overrides[addr]; ok {
+		return override
+	}
+
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+
+	override, ok := overrides[host]
+	if !ok {
+		return addr
+	}
+	if _, _, err := net.SplitHostPort(override); err != nil {
+		return net.JoinHostPort(override, port)
+	}
+	return override
+}
@@ -0,0 +1,85 @@
+package templating
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTemplater_Parse_HostOverride(t *testing.T) {
+	hosts := make(chan string, 1)
+	canary := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("Content-Type", "text/html")
+		hosts <- request.Host
+		writer.Write([]byte("<content>Canary</content>"))
+	}))
+	defer canary.Close()
+
+	templater := New(WithHostOverride(map[string]string{"fragments.example.com": canary.Listener.Addr().String()}))
+
+	const expected = "<html><head></head><body><><content>Canary</content></></body></html>"
+	actual, err := templater.Parse(strings.NewReader(`<html><body><fragment src="http://fragments.example.com/teaser">Bar</fragment></body></html>`))
+	assert.NoError(t, err)
+	assert.Equal(t, expected, actual)
+	assert.Equal(t, "fragments.example.com", <-hosts)
+}
+
+func TestTemplater_Parse_HostOverrideWithClient(t *testing.T) {
+	canary := httptest.NewTLSServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("Content-Type", "text/html")
+		writer.Write([]byte("<content>Canary</content>"))
+	}))
+	defer canary.Close()
+
+	const expected = "<html><head></head><body><><content>Canary</content></></body></html>"
+
+	t.Run("should keep the tls configuration of the transport", func(t *testing.T) {
+		templater := New(WithClient(canary.Client()), WithHostOverride(map[string]string{"example.com": canary.Listener.Addr().String()}))
+		actual, err := templater.Parse(strings.NewReader(`<html><body><fragment src="https://example.com/teaser">Bar</fragment></body></html>`))
+		assert.NoError(t, err)
+		assert.Equal(t, expected, actual)
+	})
+
+	t.Run("should keep a custom round tripper", func(t *testing.T) {
+		var calls int32
+		client := &http.Client{Transport: roundTripperFunc(func(request *http.Request) (*http.Response, error) {
+			atomic.AddInt32(&calls, 1)
+			return canary.Client().Transport.RoundTrip(request)
+		})}
+
+		templater := New(WithClient(client), WithHostOverride(map[string]string{"example.com": canary.Listener.Addr().String()}))
+		actual, err := templater.Parse(strings.NewReader(`<html><body><fragment src="` + canary.URL + `/teaser">Bar</fragment></body></html>`))
+		assert.NoError(t, err)
+		assert.Equal(t, expected, actual)
+		assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+	})
+}
+
+func TestOverrideAddress(t *testing.T) {
+	overrides := map[string]string{
+		"a.example.com":      "10.0.0.1:8080",
+		"b.example.com":      "10.0.0.2",
+		"c.example.com:8443": "10.0.0.3:443",
+	}
+
+	tt := []struct {
+		addr     string
+		expected string
+	}{
+		{addr: "a.example.com:80", expected: "10.0.0.1:8080"},
+		{addr: "b.example.com:443", expected: "10.0.0.2:443"},
+		{addr: "c.example.com:8443", expected: "10.0.0.3:443"},
+		{addr: "c.example.com:443", expected: "c.example.com:443"},
+		{addr: "d.example.com:80", expected: "d.example.com:80"},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.addr, func(t *testing.T) {
+			assert.Equal(t, tc.expected, overrideAddress(overrides, tc.addr))
+		})
+	}
+}
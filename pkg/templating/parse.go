@@ -0,0 +1,43 @@
+package templating
+
+import (
+	"bytes"
+	"errors"
+	"io"
+
+	"golang.org/x/net/html"
+)
+
+var (
+	ErrorParseTimeout = errors.New("parsing the fragment took too long")
+)
+
+// parseWithin parses the fragment, abandoning the parse if it takes longer than the configured
+// parse timeout. The parser can not be cancelled, so an abandoned parse finishes in the background.
+func (t *Templater) parseWithin(parse func(io.Reader) (*html.Node, error), reader io.Reader) (*html.Node, error) {
+	if t.parseTimeout <= 0 {
+		return parse(reader)
+	}
+
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	type outcome struct {
+		content *html.Node
+		err     error
+	}
+	done := make(chan outcome, 1)
+	go func() {
+		content, err := parse(bytes.NewReader(data))
+		done <- outcome{content: content, err: err}
+	}()
+
+	select {
+	case result := <-done:
+		return result.content, result.err
+	case <-t.after(t.parseTimeout):
+		return nil, ErrorParseTimeout
+	}
+}
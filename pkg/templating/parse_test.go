@@ -0,0 +1,35 @@
+package templating
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTemplater_Parse_ParseTimeout(t *testing.T) {
+	nested := strings.Repeat("<div>", 5000)
+	dummy := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("Content-Type", "text/html")
+		switch request.URL.Path {
+		case "/nested":
+			writer.Write([]byte(nested))
+		default:
+			writer.Write([]byte("<content>Foo</content>"))
+		}
+	}))
+	defer dummy.Close()
+
+	templater := New(WithParseTimeout(10 * time.Millisecond))
+
+	const expected = "<html><head></head><body><>Bar</><><content>Foo</content></></body></html>"
+	start := time.Now()
+	actual, err := templater.Parse(strings.NewReader(fmt.Sprintf(`<html><body><fragment src="%[1]s/nested">Bar</fragment><fragment src="%[1]s/flat"></fragment></body></html>`, dummy.URL)))
+	assert.NoError(t, err)
+	assert.Equal(t, expected, actual)
+	assert.Less(t, int64(time.Since(start)), int64(200*time.Millisecond))
+}
@@ -0,0 +1,57 @@
+package templating
+
+import (
+	"net/url"
+	"time"
+
+	"golang.org/x/net/html"
+)
+
+// Policy overrides the global defaults for the fragments of a host. Zero values keep the defaults.
+type Policy struct {
+	// Timeout is the time a fragment of the host may take to resolve.
+	Timeout time.Duration
+	// RetryOnEmpty is the number of times a fragment is requested again while it responds empty.
+	RetryOnEmpty int
+	// Retries is the number of times a fragment is requested again after a 5xx or network error.
+	Retries int
+}
+
+// policyFor returns the policy of the host serving the fragment, by its src or its upstream.
+func (t *Templater) policyFor(node html.Node) (Policy, bool) {
+	if len(t.hosts) == 0 {
+		return Policy{}, false
+	}
+
+	source := attribute(node, sourceAttribute)
+	if upstream, ok := t.upstreams[attribute(node, upstreamAttribute)]; ok && len(upstream.urls) > 0 {
+		source = upstream.urls[0]
+	}
+
+	location, err := url.Parse(source)
+	if err != nil {
+		return Policy{}, false
+	}
+
+	if policy, ok := t.hosts[location.Host]; ok {
+		return policy, true
+	}
+	policy, ok := t.hosts[location.Hostname()]
+	return policy, ok
+}
+
+// retriesOnEmpty returns the number of times the fragment is requested again while it responds empty.
+func (t *Templater) retriesOnEmpty(node html.Node) int {
+	if policy, ok := t.policyFor(node); ok && policy.RetryOnEmpty > 0 {
+		return policy.RetryOnEmpty
+	}
+	return t.retryOnEmpty
+}
+
+// retriesFor returns the number of times the fragment is requested again after a transient failure.
+func (t *Templater) retriesFor(node html.Node) int {
+	if policy, ok := t.policyFor(node); ok && policy.Retries > 0 {
+		return policy.Retries
+	}
+	return t.retries
+}
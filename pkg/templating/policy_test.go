@@ -0,0 +1,64 @@
+package templating
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/html"
+)
+
+func TestTemplater_Parse_HostPolicy(t *testing.T) {
+	patient := slowDummy(100*time.Millisecond, "<content>Patient</content>")
+	defer patient.Close()
+	impatient := slowDummy(100*time.Millisecond, "<content>Impatient</content>")
+	defer impatient.Close()
+
+	host := func(server string) string {
+		location, _ := url.Parse(server)
+		return location.Host
+	}
+
+	templater := New(
+		WithTimeout(50*time.Millisecond),
+		WithHostPolicy(host(patient.URL), Policy{Timeout: time.Second}),
+		WithHostPolicy(host(impatient.URL), Policy{Timeout: 20 * time.Millisecond}),
+	)
+
+	const expected = "<html><head></head><body><><content>Patient</content></><>Bar</></body></html>"
+	actual, err := templater.Parse(strings.NewReader(fmt.Sprintf(`<html><body><fragment src="%s">Foo</fragment><fragment src="%s">Bar</fragment></body></html>`, patient.URL, impatient.URL)))
+	assert.NoError(t, err)
+	assert.Equal(t, expected, actual)
+}
+
+func TestTemplater_policyFor(t *testing.T) {
+	templater := New(
+		WithTimeout(time.Second),
+		WithHostPolicy("a.example.com", Policy{Timeout: 2 * time.Second}),
+		WithHostPolicy("b.example.com:8080", Policy{Timeout: 3 * time.Second}),
+		WithUpstream("c", []string{"http://b.example.com:8080"}, StrategyRoundRobin),
+	)
+
+	tt := []struct {
+		attributes []html.Attribute
+		expected   time.Duration
+	}{
+		{attributes: []html.Attribute{{Key: "src", Val: "http://a.example.com/foo"}}, expected: 2 * time.Second},
+		{attributes: []html.Attribute{{Key: "src", Val: "http://a.example.com:9090/foo"}}, expected: 2 * time.Second},
+		{attributes: []html.Attribute{{Key: "src", Val: "http://b.example.com/foo"}}, expected: time.Second},
+		{attributes: []html.Attribute{{Key: "src", Val: "http://b.example.com:8080/foo"}}, expected: 3 * time.Second},
+		{attributes: []html.Attribute{{Key: "upstream", Val: "c"}}, expected: 3 * time.Second},
+		{attributes: []html.Attribute{{Key: "src", Val: "http://a.example.com/foo"}, {Key: "criticality", Val: "low"}}, expected: time.Second},
+	}
+
+	for _, tc := range tt {
+		t.Run("", func(t *testing.T) {
+			actual, err := templater.timeoutFor(html.Node{Attr: tc.attributes})
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+}
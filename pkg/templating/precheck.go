@@ -0,0 +1,28 @@
+package templating
+
+import (
+	"net/http"
+)
+
+// precheck requests the head of the fragment before requesting it, so the body of a failing backend
+// is never read. Backends that do not support HEAD requests are requested right away.
+// Fragments splicing error bodies with use-error-body are not prechecked, as their body is used anyway.
+func (t *Templater) precheck(req *http.Request) error {
+	head := req.Clone(req.Context())
+	head.Method = http.MethodHead
+
+	resp, err := t.httpClient().Do(head)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+	case resp.StatusCode == http.StatusNotModified:
+	case resp.StatusCode == http.StatusMethodNotAllowed, resp.StatusCode == http.StatusNotImplemented:
+	default:
+		return StatusError{StatusCode: resp.StatusCode}
+	}
+	return nil
+}
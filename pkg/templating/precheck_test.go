@@ -0,0 +1,95 @@
+package templating
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTemplater_Parse_HeadPrecheck(t *testing.T) {
+	var heads, gets int32
+	dummy := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("Content-Type", "text/html")
+		if request.Method == http.MethodHead {
+			atomic.AddInt32(&heads, 1)
+			switch request.URL.Path {
+			case "/down":
+				writer.WriteHeader(http.StatusServiceUnavailable)
+			case "/unsupported":
+				writer.WriteHeader(http.StatusMethodNotAllowed)
+			}
+			return
+		}
+
+		atomic.AddInt32(&gets, 1)
+		if request.URL.Path == "/down" {
+			writer.WriteHeader(http.StatusServiceUnavailable)
+			writer.Write([]byte("<content>maintenance</content>"))
+			return
+		}
+		writer.Write([]byte("<content>hello</content>"))
+	}))
+	defer dummy.Close()
+
+	testCases := map[string]struct {
+		options    []Option
+		path       string
+		attributes string
+		expected   string
+		heads      int32
+		gets       int32
+	}{
+		"up": {
+			options:  []Option{WithHeadPrecheck()},
+			path:     "/up",
+			expected: `<html><head></head><body><><content>hello</content></></body></html>`,
+			heads:    1,
+			gets:     1,
+		},
+		"down": {
+			options:  []Option{WithHeadPrecheck()},
+			path:     "/down",
+			expected: `<html><head></head><body><>Bar</></body></html>`,
+			heads:    1,
+		},
+		"head not allowed": {
+			options:  []Option{WithHeadPrecheck()},
+			path:     "/unsupported",
+			expected: `<html><head></head><body><><content>hello</content></></body></html>`,
+			heads:    1,
+			gets:     1,
+		},
+		"down with error body": {
+			options:    []Option{WithHeadPrecheck()},
+			path:       "/down",
+			attributes: ` use-error-body="true"`,
+			expected:   `<html><head></head><body><><content>maintenance</content></></body></html>`,
+			gets:       1,
+		},
+		"without precheck": {
+			path:     "/down",
+			expected: `<html><head></head><body><>Bar</></body></html>`,
+			gets:     1,
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			atomic.StoreInt32(&heads, 0)
+			atomic.StoreInt32(&gets, 0)
+
+			templater := New(tc.options...)
+			actual, err := templater.Parse(strings.NewReader(fmt.Sprintf(`<html><body><fragment src="%s%s"%s>Bar</fragment></body></html>`, dummy.URL, tc.path, tc.attributes)))
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, actual)
+			assert.Equal(t, tc.heads, atomic.LoadInt32(&heads))
+			assert.Equal(t, tc.gets, atomic.LoadInt32(&gets))
+		})
+	}
+}
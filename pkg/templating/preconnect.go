@@ -0,0 +1,80 @@
+package templating
+
+import (
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// connect records the origins of the fragment and of the absolute asset urls within its content.
+func (c *composition) connect(source string, content *html.Node) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.addOrigin(source)
+	visit(content, func(node *html.Node) {
+		if key, ok := assetAttribute(node); ok {
+			c.addOrigin(attribute(*node, key))
+		}
+	})
+}
+
+func (c *composition) addOrigin(rawURL string) {
+	origin, ok := originOf(rawURL)
+	if !ok {
+		return
+	}
+
+	for _, value := range c.origins {
+		if value == origin {
+			return
+		}
+	}
+	c.origins = append(c.origins, origin)
+}
+
+func originOf(rawURL string) (string, bool) {
+	if strings.HasPrefix(rawURL, "//") {
+		rawURL = "https:" + rawURL
+	}
+
+	parsed, err := url.Parse(rawURL)
+	if err != nil || parsed.Host == "" || (parsed.Scheme != "http" && parsed.Scheme != "https") {
+		return "", false
+	}
+	return parsed.Scheme + "://" + parsed.Host, true
+}
+
+// addPreconnects injects a preconnect link for every recorded origin into the head.
+func (t *Templater) addPreconnects(c *composition, root *html.Node) {
+	if len(c.origins) == 0 {
+		return
+	}
+
+	head, err := t.FindSection("head", root)
+	if err != nil {
+		return
+	}
+
+	existing := make(map[string]bool)
+	for child := head.FirstChild; child != nil; child = child.NextSibling {
+		if child.DataAtom == atom.Link && strings.EqualFold(attribute(*child, "rel"), "preconnect") {
+			existing[attribute(*child, "href")] = true
+		}
+	}
+
+	for _, origin := range c.origins {
+		if existing[origin] {
+			continue
+		}
+
+		head.AppendChild(&html.Node{
+			Type:     html.ElementNode,
+			DataAtom: atom.Link,
+			Data:     "link",
+			Attr:     []html.Attribute{{Key: "rel", Val: "preconnect"}, {Key: "href", Val: origin}},
+		})
+	}
+}
@@ -0,0 +1,37 @@
+package templating
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTemplater_Parse_PreconnectOrigins(t *testing.T) {
+	first := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("Content-Type", "text/html")
+		writer.Write([]byte(`<content><img src="/relative.png"><a href="https://elsewhere.example.com">Elsewhere</a></content>`))
+	}))
+	defer first.Close()
+	second := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("Content-Type", "text/html")
+		writer.Write([]byte(`<content><img src="https://cdn.example.com/a.png"></content>`))
+	}))
+	defer second.Close()
+
+	templater := New(WithPreconnectOrigins())
+	actual, err := templater.Parse(strings.NewReader(fmt.Sprintf(
+		`<html><head><link rel="preconnect" href="https://cdn.example.com"></head><body><fragment src="%[1]s/a"></fragment><fragment src="%[1]s/b"></fragment><fragment src="%[2]s"></fragment></body></html>`,
+		first.URL, second.URL,
+	)))
+	assert.NoError(t, err)
+
+	head := actual[:strings.Index(actual, "</head>")]
+	assert.Equal(t, 1, strings.Count(head, fmt.Sprintf(`<link rel="preconnect" href="%s"/>`, first.URL)))
+	assert.Equal(t, 1, strings.Count(head, fmt.Sprintf(`<link rel="preconnect" href="%s"/>`, second.URL)))
+	assert.Equal(t, 1, strings.Count(head, `<link rel="preconnect" href="https://cdn.example.com"/>`))
+	assert.NotContains(t, head, "elsewhere")
+}
@@ -0,0 +1,28 @@
+package templating
+
+import (
+	"net/http"
+
+	"golang.org/x/net/html"
+)
+
+// PreFetchFunc is consulted with the url of a fragment before it is requested. Returning true uses
+// the node as content of the fragment instead of requesting it, e.g. html served by an external cache.
+// Returning an error renders the fallback of the fragment.
+type PreFetchFunc func(url string) (*html.Node, bool, error)
+
+// preFetch returns the content of the source provided by the pre-fetch hook, if any. The node is
+// copied, as the hook may hand out the same node to concurrent renders.
+func (t *Templater) preFetch(source string) (*fragmentResponse, bool, error) {
+	node, ok, err := t.preFetchFunc(source)
+	if err != nil {
+		return nil, false, err
+	}
+	if !ok || node == nil {
+		return nil, false, nil
+	}
+
+	content := &html.Node{Type: html.ElementNode}
+	content.AppendChild(cloneNode(node))
+	return &fragmentResponse{content: content, header: http.Header{}, source: source, status: http.StatusOK}, true, nil
+}
@@ -0,0 +1,70 @@
+package templating
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+func TestTemplater_Parse_PreFetch(t *testing.T) {
+	var requests int32
+	dummy := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("Content-Type", "text/html")
+		atomic.AddInt32(&requests, 1)
+		writer.Write([]byte("<content>backend</content>"))
+	}))
+	defer dummy.Close()
+
+	cached := &html.Node{Type: html.ElementNode, DataAtom: atom.P, Data: "p"}
+	cached.AppendChild(&html.Node{Type: html.TextNode, Data: "cached"})
+
+	templater := New(WithPreFetch(func(url string) (*html.Node, bool, error) {
+		switch {
+		case strings.HasSuffix(url, "/cached"):
+			return cached, true, nil
+		case strings.HasSuffix(url, "/broken"):
+			return nil, false, errors.New("cache unavailable")
+		}
+		return nil, false, nil
+	}))
+
+	testCases := map[string]struct {
+		path     string
+		expected string
+		requests int32
+	}{
+		"short-circuited": {
+			path:     "/cached",
+			expected: `<html><head></head><body><><p>cached</p></></body></html>`,
+		},
+		"falls through to the backend": {
+			path:     "/teaser",
+			expected: `<html><head></head><body><><content>backend</content></></body></html>`,
+			requests: 1,
+		},
+		"error renders the fallback": {
+			path:     "/broken",
+			expected: `<html><head></head><body><>Bar</></body></html>`,
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			atomic.StoreInt32(&requests, 0)
+
+			actual, err := templater.Parse(strings.NewReader(fmt.Sprintf(`<html><body><fragment src="%s%s">Bar</fragment></body></html>`, dummy.URL, tc.path)))
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, actual)
+			assert.Equal(t, tc.requests, atomic.LoadInt32(&requests))
+		})
+	}
+}
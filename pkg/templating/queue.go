@@ -0,0 +1,26 @@
+package templating
+
+import (
+	"context"
+	"errors"
+)
+
+var (
+	ErrorQueueWait = errors.New("fragment waited too long for a request slot")
+)
+
+// enqueue waits for a request slot by the acquire function, at most for the configured queue wait.
+func (t *Templater) enqueue(ctx context.Context, acquire func(ctx context.Context) error) error {
+	if t.maxQueueWait <= 0 {
+		return acquire(ctx)
+	}
+
+	queueCtx, cancel := context.WithTimeout(ctx, t.maxQueueWait)
+	defer cancel()
+
+	err := acquire(queueCtx)
+	if err != nil && ctx.Err() == nil && errors.Is(err, context.DeadlineExceeded) {
+		return ErrorQueueWait
+	}
+	return err
+}
@@ -0,0 +1,45 @@
+package templating
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTemplater_Parse_MaxQueueWait(t *testing.T) {
+	var requests int32
+	dummy := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("Content-Type", "text/html")
+		atomic.AddInt32(&requests, 1)
+		writer.Write([]byte("<content>Foo</content>"))
+	}))
+	defer dummy.Close()
+
+	var audit bytes.Buffer
+	templater := New(WithAdaptiveConcurrency(), WithMaxQueueWait(30*time.Millisecond), WithTimeout(time.Second), WithAuditLog(&audit))
+
+	// saturate the limiter, as if other renders occupied all request slots
+	templater.adaptive.limit = 1
+	assert.NoError(t, templater.adaptive.acquire(context.Background()))
+
+	start := time.Now()
+	actual, err := templater.Parse(strings.NewReader(fmt.Sprintf(`<html><body><fragment src="%s">Bar</fragment></body></html>`, dummy.URL)))
+	assert.NoError(t, err)
+	assert.Equal(t, "<html><head></head><body><>Bar</></body></html>", actual)
+	assert.Less(t, int64(time.Since(start)), int64(500*time.Millisecond))
+	assert.Equal(t, int32(0), atomic.LoadInt32(&requests))
+
+	var report Report
+	assert.NoError(t, json.Unmarshal(audit.Bytes(), &report))
+	assert.Len(t, report.Fragments, 1)
+	assert.Equal(t, ErrorQueueWait.Error(), report.Fragments[0].Error)
+}
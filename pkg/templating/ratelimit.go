@@ -0,0 +1,38 @@
+package templating
+
+import (
+	"context"
+	"errors"
+)
+
+var (
+	ErrorRateLimited = errors.New("rate limit exceeded")
+)
+
+// wait blocks until the rate limiter permits the next fragment request. It fails right away
+// if the request would not be permitted before the deadline of the context.
+func (t *Templater) wait(ctx context.Context) error {
+	now := t.now()
+	reservation := t.limiter.ReserveN(now, 1)
+	if !reservation.OK() {
+		return ErrorRateLimited
+	}
+
+	delay := reservation.DelayFrom(now)
+	if delay <= 0 {
+		return nil
+	}
+
+	if deadline, ok := t.deadline(ctx); ok && now.Add(delay).After(deadline) {
+		reservation.CancelAt(now)
+		return ErrorRateLimited
+	}
+
+	select {
+	case <-t.after(delay):
+		return nil
+	case <-ctx.Done():
+		reservation.CancelAt(t.now())
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,51 @@
+package templating
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTemplater_ParseWithNode_RateLimit(t *testing.T) {
+	var requests int32
+	dummy := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("Content-Type", "text/html")
+		atomic.AddInt32(&requests, 1)
+		writer.Write([]byte("<content>Foo</content>"))
+	}))
+	defer dummy.Close()
+
+	document := fmt.Sprintf(`<html><body><fragment src="%[1]s">Bar</fragment><fragment src="%[1]s">Bar</fragment><fragment src="%[1]s">Bar</fragment><fragment src="%[1]s">Bar</fragment></body></html>`, dummy.URL)
+
+	t.Run("should delay the fragments beyond the burst", func(t *testing.T) {
+		atomic.StoreInt32(&requests, 0)
+		const expected = "<html><head></head><body><><content>Foo</content></><><content>Foo</content></><><content>Foo</content></><><content>Foo</content></></body></html>"
+
+		templater := New(WithRateLimit(20, 2))
+		start := time.Now()
+		actual, err := templater.Parse(strings.NewReader(document))
+		assert.NoError(t, err)
+		assert.Equal(t, expected, actual)
+		assert.GreaterOrEqual(t, int64(time.Since(start)), int64(80*time.Millisecond))
+		assert.Equal(t, int32(4), atomic.LoadInt32(&requests))
+	})
+
+	t.Run("should fall back when the limiter would wait beyond the deadline", func(t *testing.T) {
+		atomic.StoreInt32(&requests, 0)
+
+		templater := New(WithRateLimit(1, 2), WithTimeout(50*time.Millisecond))
+		start := time.Now()
+		actual, err := templater.Parse(strings.NewReader(document))
+		assert.NoError(t, err)
+		assert.Equal(t, 2, strings.Count(actual, "<><content>Foo</content></>"))
+		assert.Equal(t, 2, strings.Count(actual, "<>Bar</>"))
+		assert.Less(t, int64(time.Since(start)), int64(500*time.Millisecond))
+		assert.Equal(t, int32(2), atomic.LoadInt32(&requests))
+	})
+}
@@ -0,0 +1,44 @@
+package templating
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+type RedirectPolicy int
+
+const (
+	// RedirectFollow follows redirects of fragment requests up to the maximum number of hops.
+	RedirectFollow RedirectPolicy = iota
+	// RedirectFail renders the fallback of fragments whose request is redirected, e.g. to a login page.
+	RedirectFail
+)
+
+const defaultMaxRedirects = 10
+
+var (
+	ErrorRedirect         = errors.New("fragment request was redirected")
+	ErrorTooManyRedirects = errors.New("fragment request exceeded the maximum redirects")
+)
+
+// checkRedirect applies the redirect policy to the redirect of a request, given the requests before it.
+func (t *Templater) checkRedirect(req *http.Request, via []*http.Request) error {
+	if t.redirectPolicy == RedirectFail {
+		return fmt.Errorf("%w: %s", ErrorRedirect, req.URL)
+	}
+
+	limit := t.maxRedirects
+	if limit <= 0 {
+		limit = defaultMaxRedirects
+	}
+	if len(via) > limit {
+		return fmt.Errorf("%w: %d", ErrorTooManyRedirects, limit)
+	}
+	return nil
+}
+
+// redirected reports whether the request failed by the redirect policy, so it is not repeated.
+func redirected(err error) bool {
+	return errors.Is(err, ErrorRedirect) || errors.Is(err, ErrorTooManyRedirects)
+}
@@ -0,0 +1,106 @@
+package templating
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTemplater_Parse_RedirectPolicy(t *testing.T) {
+	var requests int32
+	dummy := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("Content-Type", "text/html")
+		atomic.AddInt32(&requests, 1)
+		switch request.URL.Path {
+		case "/moved":
+			http.Redirect(writer, request, "/renamed", http.StatusFound)
+		case "/renamed":
+			http.Redirect(writer, request, "/teaser", http.StatusMovedPermanently)
+		case "/loop":
+			http.Redirect(writer, request, "/loop", http.StatusFound)
+		default:
+			writer.Write([]byte("<content>Foo</content>"))
+		}
+	}))
+	defer dummy.Close()
+
+	testCases := map[string]struct {
+		options  []Option
+		path     string
+		expected string
+		location string
+		err      error
+		requests int32
+	}{
+		"followed by default": {
+			path:     "/moved",
+			expected: `<html><head></head><body><><content>Foo</content></></body></html>`,
+			location: "/teaser",
+			requests: 3,
+		},
+		"followed within the maximum hops": {
+			options:  []Option{WithRedirectPolicy(RedirectFollow, 2)},
+			path:     "/moved",
+			expected: `<html><head></head><body><><content>Foo</content></></body></html>`,
+			location: "/teaser",
+			requests: 3,
+		},
+		"exceeding the maximum hops": {
+			options:  []Option{WithRedirectPolicy(RedirectFollow, 1), WithRetries(2)},
+			path:     "/moved",
+			expected: `<html><head></head><body><>Bar</></body></html>`,
+			err:      ErrorTooManyRedirects,
+			requests: 2,
+		},
+		"loop": {
+			options:  []Option{WithRedirectPolicy(RedirectFollow, 0)},
+			path:     "/loop",
+			expected: `<html><head></head><body><>Bar</></body></html>`,
+			err:      ErrorTooManyRedirects,
+			requests: 11,
+		},
+		"failing on redirects": {
+			options:  []Option{WithRedirectPolicy(RedirectFail, 0), WithRetries(2)},
+			path:     "/moved",
+			expected: `<html><head></head><body><>Bar</></body></html>`,
+			err:      ErrorRedirect,
+			requests: 1,
+		},
+		"not redirected": {
+			options:  []Option{WithRedirectPolicy(RedirectFail, 0)},
+			path:     "/teaser",
+			expected: `<html><head></head><body><><content>Foo</content></></body></html>`,
+			requests: 1,
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			atomic.StoreInt32(&requests, 0)
+
+			templater := New(tc.options...)
+			actual, report, err := templater.ParseWithReport(strings.NewReader(fmt.Sprintf(`<html><body><fragment src="%s%s">Bar</fragment></body></html>`, dummy.URL, tc.path)))
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, actual)
+			assert.Equal(t, tc.requests, atomic.LoadInt32(&requests))
+			if !assert.Len(t, report.Fragments, 1) {
+				return
+			}
+
+			if tc.location != "" {
+				assert.Equal(t, dummy.URL+tc.location, report.Fragments[0].Location)
+			} else {
+				assert.Empty(t, report.Fragments[0].Location)
+			}
+			if tc.err != nil {
+				assert.Contains(t, report.Fragments[0].Error, tc.err.Error())
+			}
+		})
+	}
+}
@@ -0,0 +1,219 @@
+package templating
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/html"
+)
+
+type Cacheability string
+
+const (
+	// CacheabilityPublic marks a fragment that may be shared between users.
+	CacheabilityPublic Cacheability = "public"
+	// CacheabilityPrivate marks a fragment personalized for a user, making the composed page private.
+	CacheabilityPrivate Cacheability = "private"
+)
+
+const (
+	cacheabilityAttribute = "cacheability"
+)
+
+// StatusError is returned when a fragment responds with an unexpected status code.
+type StatusError struct {
+	StatusCode int
+}
+
+func (e StatusError) Error() string {
+	return fmt.Sprintf("could not resolve the fragment: unexpected status %d", e.StatusCode)
+}
+
+// FragmentError describes a fragment that failed to resolve and fell back.
+type FragmentError struct {
+	ID         string
+	Source     string
+	StatusCode int
+	Err        error
+}
+
+func newFragmentError(node html.Node, err error) FragmentError {
+	source := attribute(node, sourceAttribute)
+	if source == "" {
+		source = attribute(node, upstreamAttribute)
+	}
+
+	result := FragmentError{ID: attribute(node, idAttribute), Source: source, Err: err}
+	var statusError StatusError
+	if errors.As(err, &statusError) {
+		result.StatusCode = statusError.StatusCode
+	}
+	return result
+}
+
+func (e FragmentError) Error() string {
+	return fmt.Sprintf("fragment %s: %v", e.Source, e.Err)
+}
+
+func (e FragmentError) Unwrap() error {
+	return e.Err
+}
+
+// Report summarizes a single render.
+type Report struct {
+	RequestID string           `json:"request_id"`
+	Fragments []FragmentReport `json:"fragments"`
+	Duration  time.Duration    `json:"duration"`
+	// Vary lists the forwarded request headers the fragments were resolved with, see ParseResponse.
+	Vary []string `json:"vary,omitempty"`
+}
+
+// FragmentReport summarizes the resolution of a single fragment.
+type FragmentReport struct {
+	ID       string        `json:"id,omitempty"`
+	Source   string        `json:"src"`
+	Status   int           `json:"status,omitempty"`
+	Duration time.Duration `json:"duration"`
+	Fallback bool          `json:"fallback"`
+	Error    string        `json:"error,omitempty"`
+	// Raw is the unparsed response body, retained by WithRawResponses.
+	Raw []byte `json:"-"`
+	// Header is the header of the response the fragment resolved with.
+	Header http.Header `json:"-"`
+	// Location is the url the fragment was finally resolved from, if its request was redirected.
+	Location string `json:"location,omitempty"`
+	// Cacheability is the cacheability declared by the cacheability attribute of the fragment.
+	Cacheability Cacheability `json:"cacheability,omitempty"`
+}
+
+func newFragmentReport(node html.Node, response *fragmentResponse, err error, duration time.Duration) FragmentReport {
+	report := FragmentReport{
+		ID:           attribute(node, idAttribute),
+		Source:       attribute(node, sourceAttribute),
+		Duration:     duration,
+		Cacheability: cacheabilityOf(node),
+	}
+	if report.Source == "" {
+		report.Source = attribute(node, upstreamAttribute)
+	}
+
+	if errors.Is(err, ErrorNotModified) {
+		report.Status = http.StatusNotModified
+		return report
+	}
+	if err != nil {
+		report.Fallback = true
+		report.Error = err.Error()
+
+		var statusError StatusError
+		if errors.As(err, &statusError) {
+			report.Status = statusError.StatusCode
+		}
+		return report
+	}
+
+	report.Source = response.source
+	if response.location != response.source {
+		report.Location = response.location
+	}
+	report.Status = response.status
+	report.Raw = response.raw
+	report.Header = response.header
+	return report
+}
+
+// cacheabilityOf returns the cacheability declared by the fragment, empty if it declares none or an unknown one.
+func cacheabilityOf(node html.Node) Cacheability {
+	switch value := Cacheability(strings.ToLower(strings.TrimSpace(attribute(node, cacheabilityAttribute)))); value {
+	case CacheabilityPublic, CacheabilityPrivate:
+		return value
+	}
+	return ""
+}
+
+// Cacheability aggregates the cacheability declared by the fragments into the one of the composed
+// page, where the strictest wins. It is empty if no fragment declared any.
+func (r Report) Cacheability() Cacheability {
+	var result Cacheability
+	for _, fragment := range r.Fragments {
+		switch fragment.Cacheability {
+		case CacheabilityPrivate:
+			return CacheabilityPrivate
+		case CacheabilityPublic:
+			result = CacheabilityPublic
+		}
+	}
+	return result
+}
+
+// CacheControl aggregates the Cache-Control headers of all resolved fragments into the one of
+// the composed page: the smallest max-age and every restriction of any fragment, no-store if any
+// fragment forbids storing its response. Fragments that fell back or stated nothing are ignored,
+// so the result is empty if no fragment stated a policy. A fragment declared private by its
+// cacheability attribute makes the page private as well.
+func (r Report) CacheControl() string {
+	var (
+		private = r.Cacheability() == CacheabilityPrivate
+		noCache bool
+		maxAge  = -1
+	)
+	for _, fragment := range r.Fragments {
+		for _, directive := range strings.Split(fragment.Header.Get("Cache-Control"), ",") {
+			name, value, _ := strings.Cut(strings.TrimSpace(directive), "=")
+			switch strings.ToLower(name) {
+			case "no-store":
+				return "no-store"
+			case "private":
+				private = true
+			case "no-cache":
+				noCache = true
+			case "max-age":
+				if seconds, err := strconv.Atoi(strings.Trim(value, `"`)); err == nil && (maxAge < 0 || seconds < maxAge) {
+					maxAge = seconds
+				}
+			}
+		}
+	}
+
+	var directives []string
+	if private {
+		directives = append(directives, "private")
+	}
+	if noCache {
+		directives = append(directives, "no-cache")
+	}
+	if maxAge >= 0 {
+		directives = append(directives, "max-age="+strconv.Itoa(maxAge))
+	}
+	return strings.Join(directives, ", ")
+}
+
+func newRequestID() string {
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(id)
+}
+
+// auditLog writes one JSON record per render, safe for concurrent renders.
+type auditLog struct {
+	mu     sync.Mutex
+	writer io.Writer
+}
+
+func (a *auditLog) write(report Report) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	return json.NewEncoder(a.writer).Encode(report)
+}
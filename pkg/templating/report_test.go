@@ -0,0 +1,201 @@
+package templating
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/html"
+)
+
+func TestTemplater_Parse_AuditLog(t *testing.T) {
+	clock := newFakeClock()
+	dummy := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("Content-Type", "text/html")
+		clock.Advance(10 * time.Millisecond)
+		if request.URL.Path == "/broken" {
+			writer.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		writer.Write([]byte("<content>Foo</content>"))
+	}))
+	defer dummy.Close()
+
+	var audit bytes.Buffer
+	templater := New(WithClock(clock), WithAuditLog(&audit))
+
+	document := fmt.Sprintf(`<html><body><fragment id="nav" src="%[1]s/nav"></fragment><fragment id="footer" src="%[1]s/broken">Footer</fragment></body></html>`, dummy.URL)
+	for i := 0; i < 2; i++ {
+		_, err := templater.Parse(strings.NewReader(document))
+		assert.NoError(t, err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(audit.String()), "\n")
+	if !assert.Len(t, lines, 2, "one record per render") {
+		return
+	}
+
+	var reports []Report
+	for _, line := range lines {
+		var report Report
+		assert.NoError(t, json.Unmarshal([]byte(line), &report))
+		reports = append(reports, report)
+	}
+
+	assert.NotEmpty(t, reports[0].RequestID)
+	assert.NotEqual(t, reports[0].RequestID, reports[1].RequestID)
+	assert.Equal(t, 20*time.Millisecond, reports[0].Duration)
+	assert.ElementsMatch(t, []FragmentReport{
+		{ID: "nav", Source: dummy.URL + "/nav", Status: http.StatusOK, Duration: 10 * time.Millisecond},
+		{ID: "footer", Source: dummy.URL + "/broken", Status: http.StatusInternalServerError, Duration: 10 * time.Millisecond, Fallback: true, Error: "could not resolve the fragment: unexpected status 500"},
+	}, reports[0].Fragments)
+}
+
+func TestTemplater_ParseWithReport_RawResponses(t *testing.T) {
+	const body = "\uFEFF  <content>Foo</content>\n"
+	dummy := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("Content-Type", "text/html")
+		writer.Write([]byte(body))
+	}))
+	defer dummy.Close()
+
+	document := fmt.Sprintf(`<html><body><fragment id="foo" src="%s"></fragment></body></html>`, dummy.URL)
+
+	t.Run("should retain the raw response", func(t *testing.T) {
+		templater := New(WithRawResponses())
+		actual, report, err := templater.ParseWithReport(strings.NewReader(document))
+		assert.NoError(t, err)
+		assert.Equal(t, "<html><head></head><body><>  <content>Foo</content>\n</></body></html>", actual)
+		assert.Len(t, report.Fragments, 1)
+		assert.Equal(t, []byte(body), report.Fragments[0].Raw)
+	})
+
+	t.Run("should not retain the raw response by default", func(t *testing.T) {
+		templater := New()
+		_, report, err := templater.ParseWithReport(strings.NewReader(document))
+		assert.NoError(t, err)
+		assert.Len(t, report.Fragments, 1)
+		assert.Nil(t, report.Fragments[0].Raw)
+	})
+}
+
+func TestTemplater_ParseWithResult(t *testing.T) {
+	dummy := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("Content-Type", "text/html")
+		writer.WriteHeader(http.StatusBadGateway)
+	}))
+	defer dummy.Close()
+
+	source := dummy.URL + "/broken"
+	templater := New()
+	actual, errs, err := templater.ParseWithResult(strings.NewReader(fmt.Sprintf(`<html><body><fragment src="%s">Bar</fragment></body></html>`, source)))
+	assert.NoError(t, err)
+	assert.Equal(t, "<html><head></head><body><>Bar</></body></html>", actual)
+	if assert.Len(t, errs, 1) {
+		assert.Equal(t, source, errs[0].Source)
+		assert.Equal(t, http.StatusBadGateway, errs[0].StatusCode)
+		assert.ErrorIs(t, errs[0], errs[0].Err)
+		assert.Equal(t, StatusError{StatusCode: http.StatusBadGateway}, errs[0].Err)
+	}
+}
+
+func TestTemplater_ParseWithNodeReport_CacheControl(t *testing.T) {
+	dummy := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("Content-Type", "text/html")
+		writer.Header().Set("Cache-Control", request.URL.Query().Get("cache"))
+		writer.Write([]byte("<content>Foo</content>"))
+	}))
+	defer dummy.Close()
+
+	testCases := map[string]struct {
+		policies []string
+		expected string
+	}{
+		"minimum max-age": {
+			policies: []string{"max-age=300", "public, max-age=60"},
+			expected: "max-age=60",
+		},
+		"private": {
+			policies: []string{"max-age=300", "private, max-age=600"},
+			expected: "private, max-age=300",
+		},
+		"no-store": {
+			policies: []string{"max-age=300", "no-store", "private"},
+			expected: "no-store",
+		},
+		"unstated": {
+			policies: []string{"", ""},
+			expected: "",
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			var fragments strings.Builder
+			for _, policy := range tc.policies {
+				fragments.WriteString(fmt.Sprintf(`<fragment src="%s?cache=%s"></fragment>`, dummy.URL, url.QueryEscape(policy)))
+			}
+			document, err := html.Parse(strings.NewReader("<html><body>" + fragments.String() + "</body></html>"))
+			assert.NoError(t, err)
+
+			templater := New()
+			report := templater.ParseWithNodeReport(document)
+			assert.Len(t, report.Fragments, len(tc.policies))
+			assert.Equal(t, tc.expected, report.CacheControl())
+		})
+	}
+}
+
+func TestTemplater_ParseWithReport_Cacheability(t *testing.T) {
+	dummy := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("Content-Type", "text/html")
+		writer.Header().Set("Cache-Control", "max-age=60")
+		writer.Write([]byte("<content>Foo</content>"))
+	}))
+	defer dummy.Close()
+
+	testCases := map[string]struct {
+		attributes   []string
+		expected     Cacheability
+		cacheControl string
+	}{
+		"a single private fragment": {
+			attributes:   []string{`cacheability="public"`, `cacheability="private"`, ``},
+			expected:     CacheabilityPrivate,
+			cacheControl: "private, max-age=60",
+		},
+		"all public": {
+			attributes:   []string{`cacheability="public"`, `cacheability="PUBLIC"`},
+			expected:     CacheabilityPublic,
+			cacheControl: "max-age=60",
+		},
+		"undeclared": {
+			attributes:   []string{``, `cacheability="unknown"`},
+			cacheControl: "max-age=60",
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			var fragments strings.Builder
+			for _, attributes := range tc.attributes {
+				fragments.WriteString(fmt.Sprintf(`<fragment src="%s" %s></fragment>`, dummy.URL, attributes))
+			}
+
+			templater := New()
+			_, report, err := templater.ParseWithReport(strings.NewReader("<html><body>" + fragments.String() + "</body></html>"))
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, report.Cacheability())
+			assert.Equal(t, tc.cacheControl, report.CacheControl())
+		})
+	}
+}
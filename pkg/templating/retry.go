@@ -0,0 +1,137 @@
+package templating
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html"
+)
+
+var (
+	ErrorEmptyResponse = errors.New("fragment responded with empty content")
+)
+
+// attempt requests the fragment from the source, retrying after transient failures and while the
+// backend responds with empty content, up to the configured number of retries for either with the
+// configured backoff and within the deadline of the context. Sources that recently failed all
+// their attempts, or whose host circuit is open, fall back right away. Failures after the context of
+// the render is done are not remembered, since the caller rather than the backend gave up, while
+// backends exceeding the timeout of the fragment are.
+// A positive timeout bounds every single request.
+func (t *Templater) attempt(ctx context.Context, c *composition, node html.Node, source string, timeout time.Duration) (*fragmentResponse, error) {
+	if err := t.allow(source, t.now()); err != nil {
+		return nil, err
+	}
+	if t.failures != nil {
+		if err, ok := t.failures.lookup(t.cacheKey(source), t.now()); ok {
+			return nil, err
+		}
+	}
+
+	result, err := t.retry(ctx, c, node, source, timeout)
+	t.recordBreakers(source, err, t.now())
+	if t.failures != nil && c.ctx.Err() == nil {
+		t.failures.store(t.cacheKey(source), err, t.now())
+	}
+	return result, err
+}
+
+// retry requests the fragment until it succeeds with content or runs out of retries.
+// Fragments requested with a method that is not idempotent are requested only once.
+func (t *Templater) retry(ctx context.Context, c *composition, node html.Node, source string, timeout time.Duration) (*fragmentResponse, error) {
+	if !idempotent(node) {
+		return t.try(ctx, c, node, source, timeout)
+	}
+
+	onEmpty, onFailure := t.retriesOnEmpty(node), t.retriesFor(node)
+	result, err := t.try(ctx, c, node, source, timeout)
+	for retries := 0; ; retries++ {
+		reason := err
+		switch {
+		case err != nil && retries < onFailure && transient(err):
+		case err == nil && retries < onEmpty && isEmpty(result.content):
+			reason = ErrorEmptyResponse
+		default:
+			return result, err
+		}
+
+		if !t.backOff(ctx, retries+1, reason) {
+			return result, err
+		}
+		result, err = t.try(ctx, c, node, source, timeout)
+	}
+}
+
+// transient reports whether the request failed with a server error or on the network, so it may
+// succeed when repeated. Client errors and requests cancelled by the caller are not retried.
+func transient(err error) bool {
+	if redirected(err) || errors.Is(err, context.Canceled) {
+		return false
+	}
+
+	var statusError StatusError
+	if errors.As(err, &statusError) {
+		return statusError.StatusCode >= http.StatusInternalServerError
+	}
+
+	var urlError *url.Error
+	return errors.As(err, &urlError)
+}
+
+// try requests the fragment once, bounded by the timeout if positive, unless a fresh response
+// of it is cached or the pre-fetch hook provides it. Requests wait for the adaptive concurrency limit.
+func (t *Templater) try(ctx context.Context, c *composition, node html.Node, source string, timeout time.Duration) (*fragmentResponse, error) {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = t.withTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	var key string
+	if t.cacheable(node) {
+		key = t.responseKey(c, node, source)
+		result, ok := t.cache.lookup(key, t.now())
+		traceCache(ctx, ok)
+		if ok {
+			c.vary()
+			identify(node, result)
+			return result, nil
+		}
+	}
+
+	if t.preFetchFunc != nil {
+		if result, ok, err := t.preFetch(source); err != nil || ok {
+			return result, err
+		}
+	}
+
+	if t.adaptive != nil {
+		if err := t.enqueue(ctx, t.adaptive.acquire); err != nil {
+			return nil, err
+		}
+		start := t.now()
+		defer func() {
+			t.adaptive.release(t.since(start))
+		}()
+	}
+
+	result, err := t.request(ctx, c, node, source)
+	if key != "" && err == nil && result.status == http.StatusOK && !isEmpty(result.content) {
+		t.cache.store(key, result, t.now())
+	}
+	return result, err
+}
+
+// isEmpty reports whether the content has no children other than whitespace.
+func isEmpty(content *html.Node) bool {
+	for child := content.FirstChild; child != nil; child = child.NextSibling {
+		if child.Type != html.TextNode || strings.TrimSpace(child.Data) != "" {
+			return false
+		}
+	}
+	return true
+}
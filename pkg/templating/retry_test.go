@@ -0,0 +1,163 @@
+package templating
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTemplater_Parse_RetryOnEmpty(t *testing.T) {
+	testCases := map[string]struct {
+		options  []Option
+		empty    int32
+		expected string
+		requests int32
+	}{
+		"retry produces the content": {
+			options:  []Option{WithRetryOnEmpty(1)},
+			empty:    1,
+			expected: `<html><head></head><body><><content>hello</content></></body></html>`,
+			requests: 2,
+		},
+		"without retries": {
+			empty:    1,
+			expected: "<html><head></head><body><> \n </></body></html>",
+			requests: 1,
+		},
+		"retries exhausted": {
+			options:  []Option{WithRetryOnEmpty(2)},
+			empty:    5,
+			expected: "<html><head></head><body><> \n </></body></html>",
+			requests: 3,
+		},
+		"content is not retried": {
+			options:  []Option{WithRetryOnEmpty(2)},
+			expected: `<html><head></head><body><><content>hello</content></></body></html>`,
+			requests: 1,
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			var requests int32
+			dummy := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+				writer.Header().Set("Content-Type", "text/html")
+				if atomic.AddInt32(&requests, 1) <= tc.empty {
+					writer.Write([]byte(" \n "))
+					return
+				}
+				writer.Write([]byte(`<content>hello</content>`))
+			}))
+			defer dummy.Close()
+
+			templater := New(tc.options...)
+			actual, err := templater.Parse(strings.NewReader(fmt.Sprintf(`<html><body><fragment src="%s"></fragment></body></html>`, dummy.URL)))
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, actual)
+			assert.Equal(t, tc.requests, atomic.LoadInt32(&requests))
+		})
+	}
+}
+
+func TestTemplater_Parse_Retries(t *testing.T) {
+	testCases := map[string]struct {
+		options    []Option
+		attributes string
+		status     int
+		failures   int32
+		reset      bool
+		expected   string
+		requests   int32
+	}{
+		"server errors are retried": {
+			options:  []Option{WithRetries(2)},
+			status:   http.StatusServiceUnavailable,
+			failures: 2,
+			expected: `<html><head></head><body><><content>hello</content></></body></html>`,
+			requests: 3,
+		},
+		"network errors are retried": {
+			options:  []Option{WithRetries(1)},
+			reset:    true,
+			failures: 1,
+			expected: `<html><head></head><body><><content>hello</content></></body></html>`,
+			requests: 2,
+		},
+		"client errors are not retried": {
+			options:  []Option{WithRetries(2)},
+			status:   http.StatusNotFound,
+			failures: 1,
+			expected: `<html><head></head><body><>Bar</></body></html>`,
+			requests: 1,
+		},
+		"retries exhausted": {
+			options:  []Option{WithRetries(2), WithBackoff(ConstantBackoff(10 * time.Millisecond))},
+			status:   http.StatusBadGateway,
+			failures: 5,
+			expected: `<html><head></head><body><>Bar</></body></html>`,
+			requests: 3,
+		},
+		"non-idempotent methods are not retried": {
+			options:    []Option{WithRetries(2)},
+			attributes: ` method="post"`,
+			status:     http.StatusServiceUnavailable,
+			failures:   1,
+			expected:   `<html><head></head><body><>Bar</></body></html>`,
+			requests:   1,
+		},
+		"idempotent posts are retried": {
+			options:    []Option{WithRetries(2)},
+			attributes: ` method="post" idempotent="true"`,
+			status:     http.StatusServiceUnavailable,
+			failures:   1,
+			expected:   `<html><head></head><body><><content>hello</content></></body></html>`,
+			requests:   2,
+		},
+		"without retries": {
+			status:   http.StatusServiceUnavailable,
+			failures: 1,
+			expected: `<html><head></head><body><>Bar</></body></html>`,
+			requests: 1,
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			var requests int32
+			dummy := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+				writer.Header().Set("Content-Type", "text/html")
+				expected := http.MethodGet
+				if tc.attributes != "" {
+					expected = http.MethodPost
+				}
+				assert.Equal(t, expected, request.Method)
+
+				if atomic.AddInt32(&requests, 1) <= tc.failures {
+					if tc.reset {
+						conn, _, _ := writer.(http.Hijacker).Hijack()
+						conn.Close()
+						return
+					}
+					writer.WriteHeader(tc.status)
+					return
+				}
+				writer.Write([]byte(`<content>hello</content>`))
+			}))
+			defer dummy.Close()
+
+			templater := New(tc.options...)
+			actual, err := templater.Parse(strings.NewReader(fmt.Sprintf(`<html><body><fragment src="%s"%s>Bar</fragment></body></html>`, dummy.URL, tc.attributes)))
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, actual)
+			assert.Equal(t, tc.requests, atomic.LoadInt32(&requests))
+		})
+	}
+}
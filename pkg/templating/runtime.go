@@ -0,0 +1,27 @@
+package templating
+
+import (
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// addRuntimeScript injects the configured runtime script hydrating deferred fragments, if the
+// composed document contains any. A runtime script already present is not added again.
+func (t *Templater) addRuntimeScript(root *html.Node) {
+	var pending bool
+	visit(root, func(node *html.Node) {
+		if _, ok := lookupAttribute(*node, deferredAttribute); ok && node.Type == html.ElementNode {
+			pending = true
+		}
+	})
+	if !pending {
+		return
+	}
+
+	t.AddScript(root, &html.Node{
+		Type:     html.ElementNode,
+		DataAtom: atom.Script,
+		Data:     "script",
+		Attr:     []html.Attribute{{Key: sourceAttribute, Val: t.runtimeScript}},
+	})
+}
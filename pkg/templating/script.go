@@ -0,0 +1,62 @@
+package templating
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// hoistScripts moves the executable scripts of the fragment content into the head of the document
+// of the element, keeping their order.
+func (t *Templater) hoistScripts(element, fragment *html.Node) {
+	var scripts []*html.Node
+	visit(fragment, func(node *html.Node) {
+		if node.DataAtom == atom.Script && executable(*node) {
+			scripts = append(scripts, node)
+		}
+	})
+
+	for _, script := range scripts {
+		t.AddScript(element, script)
+		script.Parent.RemoveChild(script)
+	}
+}
+
+// containsScript reports whether the document of the node contains a script matching the script,
+// external scripts by src and, if enabled, inline scripts by the hash of their content.
+func (t *Templater) containsScript(node, script *html.Node) bool {
+	source := attribute(*script, sourceAttribute)
+	if source == "" && !t.dedupInlineScripts {
+		return false
+	}
+	digest := scriptDigest(script)
+
+	for node.Parent != nil {
+		node = node.Parent
+	}
+
+	var found bool
+	visit(node, func(node *html.Node) {
+		if node == script || node.DataAtom != atom.Script || attribute(*node, sourceAttribute) != source {
+			return
+		}
+		if source != "" || scriptDigest(node) == digest {
+			found = true
+		}
+	})
+	return found
+}
+
+// scriptDigest returns the hex encoded sha256 hash of the content of the script.
+func scriptDigest(script *html.Node) string {
+	sum := sha256.Sum256([]byte(textContent(script)))
+	return hex.EncodeToString(sum[:])
+}
+
+// executable reports whether the script element holds code rather than data like json.
+func executable(script html.Node) bool {
+	return !strings.Contains(strings.ToLower(attribute(script, "type")), "json")
+}
@@ -0,0 +1,90 @@
+package templating
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTemplater_Parse_Scripts(t *testing.T) {
+	dummy := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("Content-Type", "text/html")
+		switch request.URL.Path {
+		case "/cart":
+			writer.Write([]byte(`<content><script src="https://cdn.example.com/lib.js"></script><script>cart()</script>Cart</content>`))
+		case "/teaser":
+			writer.Write([]byte(`<content><script src="https://cdn.example.com/lib.js"></script><script type="application/ld+json">{}</script>Teaser</content>`))
+		}
+	}))
+	defer dummy.Close()
+
+	testCases := map[string]struct {
+		input    string
+		expected string
+	}{
+		"hoisted into the head": {
+			input:    fmt.Sprintf(`<html><body><fragment src="%s/cart"></fragment></body></html>`, dummy.URL),
+			expected: `<html><head><script src="https://cdn.example.com/lib.js"></script><script>cart()</script></head><body><><content>Cart</content></></body></html>`,
+		},
+		"deduplicated by src": {
+			input:    fmt.Sprintf(`<html><body><fragment src="%s/cart"></fragment><div><fragment src="%s/teaser"></fragment></div></body></html>`, dummy.URL, dummy.URL),
+			expected: `<html><head><script src="https://cdn.example.com/lib.js"></script><script>cart()</script></head><body><><content>Cart</content></><div><><content><script type="application/ld+json">{}</script>Teaser</content></></div></body></html>`,
+		},
+		"already present in the document": {
+			input:    fmt.Sprintf(`<html><head><script src="https://cdn.example.com/lib.js"></script></head><body><fragment src="%s/teaser"></fragment></body></html>`, dummy.URL),
+			expected: `<html><head><script src="https://cdn.example.com/lib.js"></script></head><body><><content><script type="application/ld+json">{}</script>Teaser</content></></body></html>`,
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			templater := New()
+			actual, err := templater.Parse(strings.NewReader(tc.input))
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+}
+
+func TestTemplater_Parse_InlineScriptDedup(t *testing.T) {
+	dummy := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("Content-Type", "text/html")
+		switch request.URL.Path {
+		case "/cart":
+			writer.Write([]byte(`<content><script>bootstrap()</script>Cart</content>`))
+		case "/teaser":
+			writer.Write([]byte(`<content><script>bootstrap()</script><script>teaser()</script>Teaser</content>`))
+		}
+	}))
+	defer dummy.Close()
+
+	input := fmt.Sprintf(`<html><body><fragment src="%s/cart"></fragment><fragment src="%s/teaser"></fragment></body></html>`, dummy.URL, dummy.URL)
+
+	testCases := map[string]struct {
+		options  []Option
+		expected string
+	}{
+		"deduplicated by content": {
+			options:  []Option{WithInlineScriptDedup()},
+			expected: `<html><head><script>bootstrap()</script><script>teaser()</script></head><body><><content>Cart</content></><><content>Teaser</content></></body></html>`,
+		},
+		"kept without deduplication": {
+			expected: `<html><head><script>bootstrap()</script><script>bootstrap()</script><script>teaser()</script></head><body><><content>Cart</content></><><content>Teaser</content></></body></html>`,
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			templater := New(tc.options...)
+			actual, err := templater.Parse(strings.NewReader(input))
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+}
@@ -0,0 +1,32 @@
+package templating
+
+import (
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+const (
+	slotAttribute = "slot"
+)
+
+// slotFor returns the layout slot named by the slot attribute of the fragment, nil if the fragment
+// has no slot or the layout does not define it.
+func slotFor(element *html.Node) *html.Node {
+	name, ok := lookupAttribute(*element, slotAttribute)
+	if !ok {
+		return nil
+	}
+
+	root := element
+	for root.Parent != nil {
+		root = root.Parent
+	}
+
+	var slot *html.Node
+	visit(root, func(node *html.Node) {
+		if slot == nil && node.Type == html.ElementNode && node.DataAtom == atom.Slot && attribute(*node, "name") == name {
+			slot = node
+		}
+	})
+	return slot
+}
@@ -0,0 +1,47 @@
+package templating
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTemplater_Parse_Slot(t *testing.T) {
+	dummy := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("Content-Type", "text/html")
+		writer.Write([]byte("<content>Foo</content>"))
+	}))
+	defer dummy.Close()
+
+	tt := map[string]struct {
+		document string
+		expected string
+	}{
+		"fills the slot": {
+			document: `<html><body><main><fragment slot="sidebar" src="%s">Bar</fragment></main><aside><slot name="sidebar">Empty</slot></aside></body></html>`,
+			expected: `<html><head></head><body><main></main><aside><><content>Foo</content></></aside></body></html>`,
+		},
+		"splices in place without slot": {
+			document: `<html><body><main><fragment slot="footer" src="%s">Bar</fragment></main><aside><slot name="sidebar">Empty</slot></aside></body></html>`,
+			expected: `<html><head></head><body><main><><content>Foo</content></></main><aside><slot name="sidebar">Empty</slot></aside></body></html>`,
+		},
+		"fills the slot with the wrapper": {
+			document: `<html><body><fragment slot="sidebar" wrapper="section" src="%s">Bar</fragment><slot name="sidebar"></slot></body></html>`,
+			expected: `<html><head></head><body><section><content>Foo</content></section></body></html>`,
+		},
+	}
+
+	for name, tc := range tt {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			var templater Templater
+			actual, err := templater.Parse(strings.NewReader(fmt.Sprintf(tc.document, dummy.URL)))
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+}
@@ -0,0 +1,149 @@
+package templating
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"text/template"
+	"text/template/parse"
+)
+
+var (
+	ErrorInvalidSource      = errors.New("invalid fragment src template")
+	ErrorUnresolvedVariable = errors.New("unresolved variable in fragment src")
+)
+
+// variablePattern matches the {{name}} placeholders of a fragment src.
+var variablePattern = regexp.MustCompile(`\{\{\s*([A-Za-z_][A-Za-z0-9_]*)\s*\}\}`)
+
+// interpolateSource substitutes the {{name}} placeholders of the src by the variables of the render.
+// Values are escaped as path segment, or as query value after the ? or within the host, so they can
+// not change the structure of the url. A placeholder without a variable fails with ErrorUnresolvedVariable,
+// so the fragment falls back instead of being requested from a literal placeholder.
+func interpolateSource(source string, vars map[string]string) (string, error) {
+	var builder strings.Builder
+	last := 0
+	for _, match := range variablePattern.FindAllStringSubmatchIndex(source, -1) {
+		name := source[match[2]:match[3]]
+		value, ok := vars[name]
+		if !ok {
+			return "", fmt.Errorf("%w: %s", ErrorUnresolvedVariable, name)
+		}
+
+		builder.WriteString(source[last:match[0]])
+		if inQuery(source[:match[0]]) {
+			builder.WriteString(url.QueryEscape(value))
+		} else {
+			builder.WriteString(escapeSegment(value))
+		}
+		last = match[1]
+	}
+	builder.WriteString(source[last:])
+	return builder.String(), nil
+}
+
+// inQuery reports whether a value following the prefix of a url is escaped as query value, which is
+// the case after the ? and before the path, as the host must not gain an @, : or / either.
+func inQuery(prefix string) bool {
+	if strings.Contains(prefix, "?") {
+		return true
+	}
+	if i := strings.Index(prefix, "//"); i >= 0 {
+		return !strings.Contains(prefix[i+2:], "/")
+	}
+	return false
+}
+
+// escapeSegment escapes the value as path segment, including the dot segments . and ..
+// that would otherwise be resolved against the path.
+func escapeSegment(value string) string {
+	if value == "." || value == ".." {
+		return strings.ReplaceAll(value, ".", "%2E")
+	}
+	return url.PathEscape(value)
+}
+
+// evaluateSource executes the src of a fragment as text/template against the variables of the render.
+// Missing variables evaluate to the empty string. Printed values are escaped like the placeholders of
+// interpolateSource, except at the start of the src, where they are the origin of the url.
+func evaluateSource(source string, vars map[string]string) (string, error) {
+	if !strings.Contains(source, "{{") {
+		return source, nil
+	}
+
+	tmpl, err := template.New(sourceAttribute).Option("missingkey=zero").Funcs(sourceFuncs).Parse(source)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrorInvalidSource, err)
+	}
+	escapeActions(tmpl.Tree.Root, "")
+
+	if vars == nil {
+		vars = map[string]string{}
+	}
+
+	var builder strings.Builder
+	if err := tmpl.Execute(&builder, vars); err != nil {
+		return "", fmt.Errorf("%w: %v", ErrorInvalidSource, err)
+	}
+	return strings.TrimSpace(builder.String()), nil
+}
+
+// sourceFuncs are the functions available to src templates, next to the builtin urlquery.
+var sourceFuncs = template.FuncMap{
+	"pathescape": func(value any) string {
+		return escapeSegment(fmt.Sprint(value))
+	},
+}
+
+// escapeActions pipes the value of every action printing it into pathescape, or urlquery within the
+// query or host, depending on the text preceding the action, unless the action escapes it already. It returns the text preceding what follows
+// the list, with printed values standing in as placeholder.
+func escapeActions(list *parse.ListNode, prefix string) string {
+	if list == nil {
+		return prefix
+	}
+
+	for _, node := range list.Nodes {
+		switch node := node.(type) {
+		case *parse.TextNode:
+			prefix += string(node.Text)
+		case *parse.ActionNode:
+			if len(node.Pipe.Decl) > 0 {
+				continue
+			}
+			if strings.TrimSpace(prefix) != "" && !escaped(node.Pipe) {
+				escaper := "pathescape"
+				if inQuery(prefix) {
+					escaper = "urlquery"
+				}
+				node.Pipe.Cmds = append(node.Pipe.Cmds, &parse.CommandNode{
+					NodeType: parse.NodeCommand,
+					Pos:      node.Pos,
+					Args:     []parse.Node{parse.NewIdentifier(escaper).SetPos(node.Pos)},
+				})
+			}
+			prefix += "value"
+		case *parse.IfNode:
+			prefix = escapeBranches(node.BranchNode, prefix)
+		case *parse.RangeNode:
+			prefix = escapeBranches(node.BranchNode, prefix)
+		case *parse.WithNode:
+			prefix = escapeBranches(node.BranchNode, prefix)
+		}
+	}
+	return prefix
+}
+
+// escaped reports whether the pipeline already ends with an escaper.
+func escaped(pipe *parse.PipeNode) bool {
+	last := pipe.Cmds[len(pipe.Cmds)-1]
+	identifier, ok := last.Args[0].(*parse.IdentifierNode)
+	return ok && (identifier.Ident == "pathescape" || identifier.Ident == "urlquery")
+}
+
+func escapeBranches(branch parse.BranchNode, prefix string) string {
+	escapeActions(branch.ElseList, prefix)
+	return escapeActions(branch.List, prefix)
+}
@@ -0,0 +1,151 @@
+package templating
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTemplater_ParseWithVars_TemplateSrc(t *testing.T) {
+	dummy := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("Content-Type", "text/html")
+		writer.Write([]byte("<content>" + strings.TrimPrefix(request.URL.Path, "/") + "</content>"))
+	}))
+	defer dummy.Close()
+
+	const document = `<html><body><fragment src="{{if .mobile}}{{.origin}}/mobile{{else}}{{.origin}}/desktop{{end}}">Bar</fragment></body></html>`
+
+	tt := map[string]struct {
+		options  []Option
+		vars     map[string]string
+		expected string
+	}{
+		"mobile": {
+			options:  []Option{WithTemplateSrc()},
+			vars:     map[string]string{"mobile": "true", "origin": dummy.URL},
+			expected: "<html><head></head><body><><content>mobile</content></></body></html>",
+		},
+		"desktop": {
+			options:  []Option{WithTemplateSrc()},
+			vars:     map[string]string{"origin": dummy.URL},
+			expected: "<html><head></head><body><><content>desktop</content></></body></html>",
+		},
+		"disabled": {
+			vars:     map[string]string{"mobile": "true", "origin": dummy.URL},
+			expected: "<html><head></head><body><>Bar</></body></html>",
+		},
+	}
+
+	for name, tc := range tt {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			templater := New(tc.options...)
+			actual, err := templater.ParseWithVars(strings.NewReader(document), tc.vars)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+}
+
+func TestTemplater_ParseWithVars_Interpolation(t *testing.T) {
+	var requests int32
+	dummy := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("Content-Type", "text/html")
+		atomic.AddInt32(&requests, 1)
+		writer.Write([]byte("<content>" + request.URL.EscapedPath() + "</content>"))
+	}))
+	defer dummy.Close()
+
+	document := `<html><body><fragment src="` + dummy.URL + `/user/{{userID}}/widget">Bar</fragment></body></html>`
+
+	tt := map[string]struct {
+		vars     map[string]string
+		expected string
+		requests int32
+	}{
+		"resolved": {
+			vars:     map[string]string{"userID": "42"},
+			expected: "<html><head></head><body><><content>/user/42/widget</content></></body></html>",
+			requests: 1,
+		},
+		"escaped": {
+			vars:     map[string]string{"userID": "../admin?x=y#a"},
+			expected: "<html><head></head><body><><content>/user/..%2Fadmin%3Fx=y%23a/widget</content></></body></html>",
+			requests: 1,
+		},
+		"unresolved": {
+			vars:     map[string]string{"user": "42"},
+			expected: "<html><head></head><body><>Bar</></body></html>",
+		},
+	}
+
+	for name, tc := range tt {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			atomic.StoreInt32(&requests, 0)
+
+			templater := New()
+			actual, err := templater.ParseWithVars(strings.NewReader(document), tc.vars)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, actual)
+			assert.Equal(t, tc.requests, atomic.LoadInt32(&requests))
+		})
+	}
+}
+
+func TestInterpolateSource(t *testing.T) {
+	tt := []struct {
+		source   string
+		expected string
+		err      error
+	}{
+		{source: "https://nav", expected: "https://nav"},
+		{source: "https://api/user/{{userID}}/widget", expected: "https://api/user/42/widget"},
+		{source: "https://api/{{ lang }}/user/{{userID}}", expected: "https://api/de/user/42"},
+		{source: "https://api/user/{{missing}}", err: ErrorUnresolvedVariable},
+		{source: "https://api/user/{{.userID}}", expected: "https://api/user/{{.userID}}"},
+		{source: "https://api/user/{{unsafe}}/widget", expected: "https://api/user/..%2Fadmin%3Fx=y%23a/widget"},
+		{source: "https://api/widget?user={{unsafe}}&lang={{lang}}", expected: "https://api/widget?user=..%2Fadmin%3Fx%3Dy%23a&lang=de"},
+		{source: "https://api/user/{{parent}}/admin", expected: "https://api/user/%2E%2E/admin"},
+		{source: "https://api{{host}}/widget", expected: "https://api%40evil.host/widget"},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.source, func(t *testing.T) {
+			actual, err := interpolateSource(tc.source, map[string]string{"lang": "de", "userID": "42", "unsafe": "../admin?x=y#a", "parent": "..", "host": "@evil.host"})
+			assert.ErrorIs(t, err, tc.err)
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+}
+
+func TestEvaluateSource(t *testing.T) {
+	tt := []struct {
+		source   string
+		expected string
+		err      error
+	}{
+		{source: "https://nav", expected: "https://nav"},
+		{source: "https://nav/{{.lang}}", expected: "https://nav/de"},
+		{source: "https://nav/{{.missing}}", expected: "https://nav/"},
+		{source: "https://nav/{{if .lang}", err: ErrorInvalidSource},
+		{source: "https://nav/user/{{.unsafe}}/widget", expected: "https://nav/user/..%2Fadmin%3Fx=y%23a/widget"},
+		{source: "https://nav/user/{{if .lang}}{{.parent}}{{end}}/admin", expected: "https://nav/user/%2E%2E/admin"},
+		{source: "https://nav/widget?user={{.unsafe}}", expected: "https://nav/widget?user=..%2Fadmin%3Fx%3Dy%23a"},
+		{source: "https://nav{{.host}}/widget", expected: "https://nav%40evil.host/widget"},
+		{source: "https://{{.host}}/widget", expected: "https://%40evil.host/widget"},
+		{source: "{{.origin}}/user/{{.unsafe | urlquery}}", expected: "https://nav/user/..%2Fadmin%3Fx%3Dy%23a"},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.source, func(t *testing.T) {
+			actual, err := evaluateSource(tc.source, map[string]string{"lang": "de", "unsafe": "../admin?x=y#a", "parent": "..", "host": "@evil.host", "origin": "https://nav"})
+			assert.ErrorIs(t, err, tc.err)
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+}
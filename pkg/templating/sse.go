@@ -0,0 +1,46 @@
+package templating
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"strings"
+)
+
+const (
+	asAttribute = "as"
+	asSSE       = "sse"
+)
+
+var (
+	ErrorNoEvent = errors.New("event stream ended without an event")
+)
+
+// firstEvent reads the data of the first event of a text/event-stream.
+func firstEvent(reader io.Reader) (io.Reader, error) {
+	var data []string
+
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			if len(data) > 0 {
+				return strings.NewReader(strings.Join(data, "\n")), nil
+			}
+			continue
+		}
+
+		if value := strings.TrimPrefix(line, "data:"); value != line {
+			data = append(data, strings.TrimPrefix(value, " "))
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(data) > 0 {
+		return strings.NewReader(strings.Join(data, "\n")), nil
+	}
+	return nil, ErrorNoEvent
+}
@@ -0,0 +1,101 @@
+package templating
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/html"
+)
+
+func TestFirstEvent(t *testing.T) {
+	tt := []struct {
+		input    string
+		expected string
+		error    error
+	}{
+		{
+			input:    "data: <p>Foo</p>\n\ndata: <p>Bar</p>\n\n",
+			expected: "<p>Foo</p>",
+		},
+		{
+			input:    ": comment\nevent: update\nid: 1\ndata: <p>\ndata:Foo</p>\n\n",
+			expected: "<p>\nFoo</p>",
+		},
+		{
+			input:    "data: <p>Foo</p>",
+			expected: "<p>Foo</p>",
+		},
+		{
+			input: ": comment\n\n",
+			error: ErrorNoEvent,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run("", func(t *testing.T) {
+			reader, err := firstEvent(strings.NewReader(tc.input))
+			assert.Equal(t, tc.error, err)
+			if err != nil {
+				return
+			}
+
+			var actual bytes.Buffer
+			actual.ReadFrom(reader)
+			assert.Equal(t, tc.expected, actual.String())
+		})
+	}
+}
+
+func TestTemplater_ParseWithNode_SSE(t *testing.T) {
+	closed := make(chan struct{})
+	dummy := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("Content-Type", "text/event-stream")
+		writer.Write([]byte("data: <p>first</p>\n\n"))
+		writer.(http.Flusher).Flush()
+
+		<-request.Context().Done()
+		close(closed)
+	}))
+	defer dummy.Close()
+
+	const expected = "<html><head></head><body><><p>first</p></></body></html>"
+	root, _ := html.Parse(strings.NewReader(fmt.Sprintf(`<html><body><fragment as="sse" src="%s">Foo</fragment></body></html>`, dummy.URL)))
+
+	templater := New()
+	templater.ParseWithNode(root)
+
+	var actual bytes.Buffer
+	html.Render(&actual, root)
+	assert.Equal(t, expected, actual.String())
+
+	select {
+	case <-closed:
+	case <-time.After(time.Second):
+		assert.Fail(t, "the event stream was not closed after the first event")
+	}
+}
+
+func TestTemplater_ParseWithReport_SSERawResponses(t *testing.T) {
+	dummy := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("Content-Type", "text/event-stream")
+		writer.Write([]byte("data: <p>first</p>\n\n"))
+		writer.(http.Flusher).Flush()
+
+		<-request.Context().Done()
+	}))
+	defer dummy.Close()
+
+	templater := New(WithRawResponses(), WithTimeout(time.Second))
+	actual, report, err := templater.ParseWithReport(strings.NewReader(fmt.Sprintf(`<html><body><fragment as="sse" src="%s">Foo</fragment></body></html>`, dummy.URL)))
+	assert.NoError(t, err)
+	assert.Equal(t, "<html><head></head><body><><p>first</p></></body></html>", actual)
+	if assert.Len(t, report.Fragments, 1) {
+		assert.Equal(t, []byte("<p>first</p>"), report.Fragments[0].Raw)
+	}
+}
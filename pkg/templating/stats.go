@@ -0,0 +1,118 @@
+package templating
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// CircuitState is the state of the circuit breaker of a fragment host.
+type CircuitState string
+
+const (
+	CircuitClosed   CircuitState = "closed"
+	CircuitOpen     CircuitState = "open"
+	CircuitHalfOpen CircuitState = "half-open"
+)
+
+// Stats is a snapshot of the internal state of the templater shared by all renders.
+type Stats struct {
+	// CacheSize is the number of fresh responses in the cache of WithCache.
+	CacheSize int
+	// CacheHits and CacheMisses count the lookups of cacheable fragments.
+	CacheHits   int64
+	CacheMisses int64
+	// InFlight is the number of fragment requests currently awaiting their response.
+	InFlight int64
+	// Circuits holds the state of every host failing recently, with WithCircuitBreaker.
+	Circuits map[string]CircuitState
+	// ConnectionCircuits holds the state of every host failing to connect recently, with WithConnectionBreaker.
+	ConnectionCircuits map[string]CircuitState
+	// ConcurrencyLimit is the current limit of WithAdaptiveConcurrency and Utilization the share
+	// of it taken by requests in flight.
+	ConcurrencyLimit int
+	Utilization      float64
+}
+
+// CacheHitRatio returns the share of cache lookups served from the cache.
+func (s Stats) CacheHitRatio() float64 {
+	if s.CacheHits+s.CacheMisses == 0 {
+		return 0
+	}
+	return float64(s.CacheHits) / float64(s.CacheHits+s.CacheMisses)
+}
+
+// Stats returns the current metrics of the templater, e.g. for capacity planning.
+func (t *Templater) Stats() Stats {
+	now := t.now()
+
+	var stats Stats
+	if t.inflight != nil {
+		stats.InFlight = atomic.LoadInt64(t.inflight)
+	}
+	if t.cache != nil {
+		stats.CacheSize, stats.CacheHits, stats.CacheMisses = t.cache.stats(now)
+	}
+	if t.breaker != nil {
+		stats.Circuits = t.breaker.states(now)
+	}
+	if t.connectionBreaker != nil {
+		stats.ConnectionCircuits = t.connectionBreaker.states(now)
+	}
+	if t.adaptive != nil {
+		var inflight int
+		stats.ConcurrencyLimit, inflight = t.adaptive.stats()
+		stats.Utilization = float64(inflight) / float64(stats.ConcurrencyLimit)
+	}
+	return stats
+}
+
+// track counts the fragment request as in flight until the returned function is called.
+func (t *Templater) track() func() {
+	if t.inflight == nil {
+		return func() {}
+	}
+	atomic.AddInt64(t.inflight, 1)
+	return func() {
+		atomic.AddInt64(t.inflight, -1)
+	}
+}
+
+// stats returns the number of fresh entries and the counted hits and misses of the cache.
+func (r *responseCache) stats(now time.Time) (size int, hits, misses int64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, entry := range r.entries {
+		if now.Before(entry.expires) {
+			size++
+		}
+	}
+	return size, r.hits, r.misses
+}
+
+// states returns the state of the circuit of every host that failed recently.
+func (b *circuitBreaker) states(now time.Time) map[string]CircuitState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	result := make(map[string]CircuitState, len(b.circuits))
+	for host, current := range b.circuits {
+		switch {
+		case current.until.IsZero():
+			result[host] = CircuitClosed
+		case now.Before(current.until):
+			result[host] = CircuitOpen
+		default:
+			result[host] = CircuitHalfOpen
+		}
+	}
+	return result
+}
+
+// stats returns the current limit and the number of requests in flight.
+func (a *adaptiveLimiter) stats() (limit, inflight int) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	return int(a.limit), a.inflight
+}
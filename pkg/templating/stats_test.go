@@ -0,0 +1,72 @@
+package templating
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTemplater_Stats(t *testing.T) {
+	var (
+		received = make(chan struct{})
+		release  = make(chan struct{})
+	)
+	dummy := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("Content-Type", "text/html")
+		switch request.URL.Path {
+		case "/slow":
+			received <- struct{}{}
+			<-release
+		}
+		writer.Write([]byte(`<content>hello</content>`))
+	}))
+	defer dummy.Close()
+
+	broken := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("Content-Type", "text/html")
+		writer.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer broken.Close()
+
+	templater := New(WithCache(time.Minute), WithAdaptiveConcurrency(), WithCircuitBreaker(1, time.Minute, time.Minute))
+	stats := templater.Stats()
+	assert.Zero(t, stats.CacheSize)
+	assert.Zero(t, stats.CacheHitRatio())
+	assert.Zero(t, stats.InFlight)
+	assert.Empty(t, stats.Circuits)
+
+	document := fmt.Sprintf(`<html><body><fragment src="%s/teaser"></fragment></body></html>`, dummy.URL)
+	for i := 0; i < 3; i++ {
+		_, err := templater.Parse(strings.NewReader(document))
+		assert.NoError(t, err)
+	}
+
+	_, err := templater.Parse(strings.NewReader(fmt.Sprintf(`<html><body><fragment src="%s"></fragment></body></html>`, broken.URL)))
+	assert.NoError(t, err)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		templater.Parse(strings.NewReader(fmt.Sprintf(`<html><body><fragment src="%s/slow"></fragment></body></html>`, dummy.URL)))
+	}()
+	<-received
+
+	stats = templater.Stats()
+	assert.Equal(t, 1, stats.CacheSize)
+	assert.Equal(t, int64(2), stats.CacheHits)
+	assert.Equal(t, int64(3), stats.CacheMisses)
+	assert.InDelta(t, 0.4, stats.CacheHitRatio(), 0.001)
+	assert.Equal(t, int64(1), stats.InFlight)
+	assert.Equal(t, map[string]CircuitState{broken.Listener.Addr().String(): CircuitOpen}, stats.Circuits)
+	assert.Greater(t, stats.ConcurrencyLimit, 0)
+	assert.InDelta(t, 1/float64(stats.ConcurrencyLimit), stats.Utilization, 0.001)
+
+	close(release)
+	<-done
+	assert.Equal(t, int64(0), templater.Stats().InFlight)
+}
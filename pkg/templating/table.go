@@ -0,0 +1,62 @@
+package templating
+
+import (
+	"io"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// tableSections are the elements whose children the parser only accepts within a table, so rows or
+// cells parsed in any other context are dropped.
+var tableSections = map[atom.Atom]bool{
+	atom.Table:    true,
+	atom.Thead:    true,
+	atom.Tbody:    true,
+	atom.Tfoot:    true,
+	atom.Tr:       true,
+	atom.Colgroup: true,
+}
+
+// tableMarkerAttribute marks template elements standing in for fragments, e.g.
+// <template data-fragment src="/rows"></template>, which the parser keeps in place within table sections.
+const tableMarkerAttribute = "data-fragment"
+
+// tableContext returns the table section the fragment is placed in, or an empty string outside of tables.
+// Parsing a document moves fragments out of table sections, as the parser does with all unknown elements
+// there, so only trees built by hand and marked template elements keep a table context.
+func tableContext(node html.Node) string {
+	if parent := node.Parent; parent != nil && parent.Type == html.ElementNode && tableSections[parent.DataAtom] {
+		return parent.Data
+	}
+	return ""
+}
+
+// parseIn parses fragment markup in the context of the table section, keeping its rows and cells.
+func parseIn(section string) func(io.Reader) (*html.Node, error) {
+	return func(reader io.Reader) (*html.Node, error) {
+		return parseFragment(reader, &html.Node{Type: html.ElementNode, DataAtom: atom.Lookup([]byte(section)), Data: section})
+	}
+}
+
+// adoptMarkedTemplates turns the template elements marked as fragments into fragment elements with the
+// given tag name, keeping their content as the fallback.
+func adoptMarkedTemplates(node *html.Node, tag string) {
+	visit(node, func(element *html.Node) {
+		if element.Type != html.ElementNode || element.DataAtom != atom.Template {
+			return
+		}
+		if _, ok := lookupAttribute(*element, tableMarkerAttribute); !ok {
+			return
+		}
+
+		element.Data, element.DataAtom = tag, atom.Lookup([]byte(tag))
+		attributes := element.Attr[:0]
+		for _, attribute := range element.Attr {
+			if attribute.Key != tableMarkerAttribute {
+				attributes = append(attributes, attribute)
+			}
+		}
+		element.Attr = attributes
+	})
+}
@@ -0,0 +1,93 @@
+package templating
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+func TestTemplater_Parse_TableContext(t *testing.T) {
+	dummy := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("Content-Type", "text/html")
+		switch request.URL.Path {
+		case "/rows":
+			writer.Write([]byte(`<tr><td>Coffee</td><td>2</td></tr><tr><td>Tea</td><td>1</td></tr>`))
+		case "/cells":
+			writer.Write([]byte(`<td>Total</td><td>3</td>`))
+		}
+	}))
+	defer dummy.Close()
+
+	testCases := map[string]struct {
+		document string
+		section  atom.Atom
+		path     string
+		expected string
+	}{
+		"rows in a tbody": {
+			document: `<html><body><table><tbody></tbody></table></body></html>`,
+			section:  atom.Tbody,
+			path:     "/rows",
+			expected: `<html><head></head><body><table><tbody><><tr><td>Coffee</td><td>2</td></tr><tr><td>Tea</td><td>1</td></tr></></tbody></table></body></html>`,
+		},
+		"cells in a row": {
+			document: `<html><body><table><tfoot><tr></tr></tfoot></table></body></html>`,
+			section:  atom.Tr,
+			path:     "/cells",
+			expected: `<html><head></head><body><table><tfoot><tr><><td>Total</td><td>3</td></></tr></tfoot></table></body></html>`,
+		},
+	}
+
+	for name, tc := range testCases {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			document, err := html.Parse(strings.NewReader(tc.document))
+			assert.NoError(t, err)
+
+			var section *html.Node
+			visit(document, func(node *html.Node) {
+				if node.DataAtom == tc.section {
+					section = node
+				}
+			})
+			section.AppendChild(&html.Node{Type: html.ElementNode, Data: fragmentIdentifier, Attr: []html.Attribute{{Key: sourceAttribute, Val: dummy.URL + tc.path}}})
+
+			templater := New()
+			templater.ParseWithNode(document)
+
+			var actual bytes.Buffer
+			assert.NoError(t, html.Render(&actual, document))
+			assert.Equal(t, tc.expected, actual.String())
+		})
+	}
+}
+
+func TestTemplater_Parse_TableMarker(t *testing.T) {
+	dummy := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("Content-Type", "text/html")
+		switch request.URL.Path {
+		case "/rows":
+			writer.Write([]byte(`<tr><td>Coffee</td><td>2</td></tr>`))
+		case "/cells":
+			writer.Write([]byte(`<td>Total</td><td>3</td>`))
+		default:
+			writer.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer dummy.Close()
+
+	document := fmt.Sprintf(`<html><body><table><tbody><template data-fragment src="%[1]s/rows"></template><template data-fragment src="%[1]s/missing"><tr><td>Unavailable</td></tr></template></tbody><tfoot><tr><template data-fragment src="%[1]s/cells"></template></tr></tfoot></table></body></html>`, dummy.URL)
+	const expected = `<html><head></head><body><table><tbody><><tr><td>Coffee</td><td>2</td></tr></><><tr><td>Unavailable</td></tr></></tbody><tfoot><tr><><td>Total</td><td>3</td></></tr></tfoot></table></body></html>`
+
+	templater := New()
+	actual, err := templater.Parse(strings.NewReader(document))
+	assert.NoError(t, err)
+	assert.Equal(t, expected, actual)
+}
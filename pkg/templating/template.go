@@ -0,0 +1,37 @@
+package templating
+
+import (
+	"golang.org/x/net/html"
+)
+
+const (
+	asTemplate = "template"
+)
+
+// composeTemplate composes the document of a template fragment in a composition of its own, with
+// fresh timeout budgets and its own head, so its fragments do not hoist into the parent document.
+// Its requests belong to the same render, sending its composition token, forwarded headers and ETags.
+// The composed body becomes the content of the fragment.
+func (t *Templater) composeTemplate(c *composition, document *html.Node) *html.Node {
+	template := t.startComposition(c.ctx, c.token)
+	template.forward = c.forward
+	template.varyBy = c.varyBy
+	template.etags = c.etags
+	template.depth = c.depth + 1
+	template.vars = c.vars
+	template.lang = c.lang
+	t.compose(template, document)
+	if len(template.varies()) > 0 {
+		c.vary()
+	}
+
+	result := &html.Node{Type: html.ElementNode}
+	body, err := t.FindSection("body", document)
+	if err != nil {
+		return result
+	}
+	for _, child := range detachChildren(body) {
+		result.AppendChild(child)
+	}
+	return result
+}
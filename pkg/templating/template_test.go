@@ -0,0 +1,64 @@
+package templating
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTemplater_Parse_Template(t *testing.T) {
+	var dummy *httptest.Server
+	dummy = httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("Content-Type", "text/html")
+		switch request.URL.Path {
+		case "/slow":
+			time.Sleep(150 * time.Millisecond)
+			writer.Write([]byte(`<content>slow</content>`))
+		case "/page":
+			fmt.Fprintf(writer, `<html><head><link rel="stylesheet" href="/page.css"></head><body><main><fragment src="%s/teaser" group="page">none</fragment></main></body></html>`, dummy.URL)
+		case "/teaser":
+			time.Sleep(100 * time.Millisecond)
+			writer.Write([]byte(`<link rel="stylesheet" href="/teaser.css"><content>hello</content>`))
+		}
+	}))
+	defer dummy.Close()
+
+	const expected = `<html><head></head><body><><content>slow</content></><><main><><content>hello</content></></main></></body></html>`
+
+	templater := New(WithTimeoutGroup("page", 200*time.Millisecond))
+	actual, err := templater.Parse(strings.NewReader(fmt.Sprintf(`<html><body><fragment id="slow" src="%s/slow" group="page"></fragment><fragment src="%s/page" as="template" depends-on="slow"></fragment></body></html>`, dummy.URL, dummy.URL)))
+	assert.NoError(t, err)
+	assert.Equal(t, expected, actual)
+}
+
+func TestTemplater_ParseRequest_Template(t *testing.T) {
+	var dummy *httptest.Server
+	dummy = httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("Content-Type", "text/html")
+		switch request.URL.Path {
+		case "/page":
+			fmt.Fprintf(writer, `<html><body><fragment src="%s/inner"></fragment></body></html>`, dummy.URL)
+		case "/inner":
+			fmt.Fprintf(writer, `<p>%s %s</p>`, request.Header.Get("Authorization"), request.Header.Get(compositionTokenHeader))
+		}
+	}))
+	defer dummy.Close()
+
+	var tokens int32
+	templater := New(WithForwardHeaders("Authorization"), WithCompositionToken(func() string {
+		tokens++
+		return fmt.Sprintf("token-%d", tokens)
+	}))
+
+	request := httptest.NewRequest(http.MethodGet, "/", nil)
+	request.Header.Set("Authorization", "Bearer x")
+	actual, err := templater.ParseRequest(request, strings.NewReader(fmt.Sprintf(`<html><body><fragment src="%s/page" as="template"></fragment></body></html>`, dummy.URL)))
+	assert.NoError(t, err)
+	assert.Equal(t, `<html><head></head><body><><><p>Bearer x token-1</p></></></body></html>`, actual)
+	assert.Equal(t, int32(1), tokens)
+}
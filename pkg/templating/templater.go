@@ -2,103 +2,696 @@ package templating
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"io"
+	"iter"
 	"net/http"
+	"slices"
+	"strings"
+	"time"
 
+	"go.opentelemetry.io/otel/trace"
 	"golang.org/x/net/html"
 	"golang.org/x/net/html/atom"
+	"golang.org/x/sync/singleflight"
+	"golang.org/x/time/rate"
 )
 
 const (
 	fragmentIdentifier = "fragment"
 	contentIdentifier  = "content"
+
+	sourceAttribute       = "src"
+	userAgentAttribute    = "user-agent"
+	groupAttribute        = "group"
+	idAttribute           = "id"
+	upstreamAttribute     = "upstream"
+	dependsOnAttribute    = "depends-on"
+	useErrorBodyAttribute = "use-error-body"
+	whenHeaderAttribute   = "when-header"
+	degradedAttribute     = "data-degraded"
+
+	compositionTokenHeader = "X-Composition-Token"
 )
 
 var (
-	ErrorNoValidInput = errors.New("no valid input")
+	ErrorNoValidInput    = errors.New("no valid input")
+	ErrorBudgetExhausted = errors.New("timeout group budget exhausted")
+	// ErrorAllFragmentsFailed is returned along with the rendered fallbacks when enabled by WithAllFailedError.
+	ErrorAllFragmentsFailed = errors.New("all fragments failed")
 )
 
 type Templater struct {
-	client http.Client
+	client                *http.Client
+	userAgent             string
+	assetCDN              func(assetURL string) string
+	timeout               time.Duration
+	groups                map[string]time.Duration
+	observer              Observer
+	slo                   time.Duration
+	emailMode             bool
+	limiter               *rate.Limiter
+	transforms            []patternTransform
+	flights               *singleflight.Group
+	mixedContent          MixedContentPolicy
+	degradedMarker        bool
+	upstreams             map[string]*upstream
+	preconnect            bool
+	foreignFragments      ForeignFragmentPolicy
+	criticality           map[string]float64
+	clock                 Clock
+	audit                 *auditLog
+	webSockets            bool
+	hardenExternalLinks   bool
+	notFound              string
+	wrapper               string
+	retryOnEmpty          int
+	compositionToken      func() string
+	collapseFallbacks     bool
+	hosts                 map[string]Policy
+	hedging               time.Duration
+	hostOverrides         map[string]string
+	modulePreload         bool
+	failures              *negativeCache
+	templateSrc           bool
+	adaptive              *adaptiveLimiter
+	fallbackFunc          FallbackFunc
+	deadlineHeader        string
+	trimLeadingWhitespace bool
+	clientRenderHeader    string
+	clientRenderValue     string
+	maxQueueWait          time.Duration
+	retainRaw             bool
+	composedMarker        bool
+	forceRecompose        bool
+	earlyHints            bool
+	headConflicts         HeadConflictPolicy
+	flags                 FlagEvaluator
+	maxConcurrency        int
+	parseTimeout          time.Duration
+	backoff               Backoff
+	allFailedError        bool
+	maxDepth              int
+	depthComment          bool
+	orderedQuery          bool
+	fallbackWrapper       string
+	warmupTimeout         time.Duration
+	fragmentTag           string
+	runtimeScript         string
+	cache                 *responseCache
+	retries               int
+	forwardHeaders        []string
+	breaker               *circuitBreaker
+	debugErrorComment     bool
+	preFetchFunc          PreFetchFunc
+	returnParam           string
+	returnURL             string
+	maxResponseBytes      int64
+	dedupInlineScripts    bool
+	contentTypes          []string
+	inflight              *int64
+	protocolRelative      ProtocolRelativePolicy
+	headPrecheck          bool
+	doctype               string
+	linkHeaders           bool
+	redirects             bool
+	redirectPolicy        RedirectPolicy
+	maxRedirects          int
+	tracer                trace.Tracer
+	connectionBreaker     *circuitBreaker
 }
 
-func New() Templater {
-	return Templater{client: *http.DefaultClient}
+func New(options ...Option) Templater {
+	templater := Templater{client: http.DefaultClient, clock: realClock{}, inflight: new(int64)}
+	for _, option := range options {
+		option(&templater)
+	}
+	return templater
 }
 
 func (t *Templater) Parse(reader io.Reader) (string, error) {
+	return t.ParseContext(context.Background(), reader)
+}
+
+// ParseContext parses and composes the document like Parse. Once the context is done,
+// pending fragment requests are abandoned and their fallback is rendered.
+func (t *Templater) ParseContext(ctx context.Context, reader io.Reader) (string, error) {
+	return t.parseDocument(t.newComposition(ctx), reader)
+}
+
+// ParseWithVars parses and composes the document like Parse, substituting escaped {{name}} placeholders in
+// the src of fragments by the variables, or evaluating it against them when enabled by WithTemplateSrc.
+func (t *Templater) ParseWithVars(reader io.Reader, vars map[string]string) (string, error) {
+	return t.ParseWithVarsContext(context.Background(), reader, vars)
+}
+
+// ParseWithVarsContext composes the document like ParseWithVars, abandoning pending fragment
+// requests once the context is done.
+func (t *Templater) ParseWithVarsContext(ctx context.Context, reader io.Reader, vars map[string]string) (string, error) {
+	c := t.newComposition(ctx)
+	c.vars = vars
+	return t.parseDocument(c, reader)
+}
+
+// ParseWithReport parses and composes the document like Parse, also returning the report of the render.
+func (t *Templater) ParseWithReport(reader io.Reader) (string, Report, error) {
+	return t.ParseWithReportContext(context.Background(), reader)
+}
+
+// ParseWithReportContext composes the document like ParseWithReport, abandoning pending fragment
+// requests once the context is done.
+func (t *Templater) ParseWithReportContext(ctx context.Context, reader io.Reader) (string, Report, error) {
+	c := t.newComposition(ctx)
+	result, err := t.parseDocument(c, reader)
+	return result, c.report(t.since(c.start)), err
+}
+
+// ParseWithResult parses and composes the document like Parse, also returning the errors of all
+// fragments that failed to resolve and fell back, so partial degradations can be logged.
+func (t *Templater) ParseWithResult(reader io.Reader) (string, []FragmentError, error) {
+	return t.ParseWithResultContext(context.Background(), reader)
+}
+
+// ParseWithResultContext composes the document like ParseWithResult, abandoning pending fragment
+// requests once the context is done.
+func (t *Templater) ParseWithResultContext(ctx context.Context, reader io.Reader) (string, []FragmentError, error) {
+	c := t.newComposition(ctx)
+	result, err := t.parseDocument(c, reader)
+	return result, c.failures, err
+}
+
+// parseDocument parses, composes and renders the document.
+func (t *Templater) parseDocument(c *composition, reader io.Reader) (string, error) {
 	root, err := html.Parse(reader)
 	if err != nil {
 		return "", ErrorNoValidInput
 	}
 
-	t.ParseWithNode(root)
+	t.parse(c, root)
 
 	var writer bytes.Buffer
 	if err := html.Render(&writer, root); err != nil {
 		return "", err
 	}
 
+	if t.allFailedError && c.failed() {
+		return writer.String(), ErrorAllFragmentsFailed
+	}
+
 	return writer.String(), nil
 }
 
+// ParseWithNode composes the parsed document in place. Unlike Parse, it accepts trees built by hand,
+// e.g. with fragments placed directly in a table section, whose content is parsed as rows or cells.
 func (t *Templater) ParseWithNode(node *html.Node) {
-	for _, element := range t.Walk(node) {
-		switch element.Data {
-		case fragmentIdentifier:
-			fragment, err := t.Resolve(*element)
-			if err != nil {
-				fragment = &html.Node{
-					Type:       html.ElementNode,
-					FirstChild: element.FirstChild,
-					LastChild:  element.LastChild,
-				}
-			}
+	t.ParseWithNodeContext(context.Background(), node)
+}
 
-			for _, value := range t.Walk(fragment) {
-				switch value.Data {
-				case fragmentIdentifier:
-					// fixme not the best way to use recursion
-					t.ParseWithNode(&html.Node{FirstChild: value})
-				case "link":
-					// fixme clean up this peace of sh*t
-					t.AddHeader(element, value)
-					value.Parent.RemoveChild(value)
-				}
-			}
+// ParseWithNodeReport composes the document like ParseWithNode, returning the report of the render,
+// e.g. to set the Cache-Control of the page by Report.CacheControl.
+func (t *Templater) ParseWithNodeReport(node *html.Node) Report {
+	return t.ParseWithNodeReportContext(context.Background(), node)
+}
+
+// ParseWithNodeReportContext composes the document like ParseWithNodeReport, abandoning pending
+// fragment requests once the context is done.
+func (t *Templater) ParseWithNodeReportContext(ctx context.Context, node *html.Node) Report {
+	c := t.newComposition(ctx)
+	t.parse(c, node)
+	return c.report(t.since(c.start))
+}
+
+// ParseWithNodeContext composes the document like ParseWithNode, abandoning pending fragment
+// requests once the context is done.
+func (t *Templater) ParseWithNodeContext(ctx context.Context, node *html.Node) {
+	t.parse(t.newComposition(ctx), node)
+}
+
+// parse composes the document and applies all post-processing of the render.
+func (t *Templater) parse(c *composition, node *html.Node) {
+	c.lang = documentLanguage(node)
+	if t.forceRecompose {
+		reopenComposed(node, t.tagName())
+	}
+	t.compose(c, node)
 
-			parent := element.Parent
-			parent.InsertBefore(fragment, element)
-			parent.RemoveChild(element)
+	if t.collapseFallbacks {
+		t.collapse(c)
+	}
+
+	if t.runtimeScript != "" {
+		t.addRuntimeScript(node)
+	}
+
+	if t.degradedMarker {
+		t.markDegraded(c, node)
+	}
+
+	if t.preconnect {
+		t.addPreconnects(c, node)
+	}
+
+	if t.modulePreload {
+		t.addModulePreloads(c, node)
+	}
+
+	if t.earlyHints || t.linkHeaders {
+		t.addHints(c, node)
+	}
+
+	if t.emailMode {
+		t.inlineStyles(c.ctx, node)
+	}
+
+	if t.debugErrorComment {
+		t.addErrorComment(c, node)
+	}
+
+	if t.doctype != "" {
+		t.setDoctype(node)
+	}
+
+	if t.audit != nil {
+		t.audit.write(c.report(t.since(c.start)))
+	}
+}
+
+// compose resolves all fragments of the node, including the fragments nested in their content.
+// Instead of recursing into nested content, it is queued as a batch of its own and composed
+// depth first, so deep nesting does not grow the call stack.
+func (t *Templater) compose(c *composition, node *html.Node) {
+	depth := c.depth
+	defer func() {
+		c.depth = depth
+	}()
+
+	queue := []batch{t.collect(node, depth)}
+	for len(queue) > 0 {
+		current := queue[len(queue)-1]
+		queue = queue[:len(queue)-1]
+
+		c.depth, c.nested = current.depth, nil
+		t.spliceBatch(c, current)
+		for i := len(c.nested) - 1; i >= 0; i-- {
+			queue = append(queue, c.nested[i])
+		}
+	}
+}
+
+// spliceBatch splices the fragments of the batch, or their fallback beyond the maximum depth.
+func (t *Templater) spliceBatch(c *composition, current batch) {
+	if c.depth >= t.depthLimit() {
+		t.exceedDepth(c, append(current.fragments, current.dependents...))
+		return
+	}
+
+	if t.maxConcurrency > 0 {
+		t.spliceConcurrently(c, current.root, current.fragments)
+	} else {
+		for _, element := range current.fragments {
+			t.splice(c, element)
+		}
+	}
+	t.spliceDependents(c, current.dependents)
+}
+
+// splice resolves the fragment element and replaces it with its content or fallback.
+func (t *Templater) splice(c *composition, element *html.Node) {
+	start := t.now()
+	response, err := t.resolve(c, *element)
+	t.apply(c, element, response, err, t.since(start))
+}
+
+// apply replaces the fragment element with its resolved content or, if it failed, its fallback.
+func (t *Templater) apply(c *composition, element *html.Node, response *fragmentResponse, err error, duration time.Duration) {
+	var fragment *html.Node
+	c.record(newFragmentReport(*element, response, err, duration))
+	if errors.Is(err, ErrorClientRender) {
+		c.settle(*element, nil)
+		deferred(element)
+		return
+	}
+	if errors.Is(err, ErrorNotModified) {
+		t.retain(c, element)
+		return
+	}
+	if err != nil {
+		if !errors.Is(err, ErrorFlagDisabled) {
+			c.degrade(*element, err)
+		}
+		c.settle(*element, nil)
+		fragment = t.fallback(element, err)
+	} else {
+		c.settle(*element, response.header)
+		c.validate(*element, response.header)
+		fragment = response.content
+	}
+
+	if err == nil && attribute(*element, asAttribute) == asTemplate {
+		fragment = t.composeTemplate(c, fragment)
+	} else {
+		if err == nil {
+			t.hoistScripts(element, fragment)
+		}
+		t.integrate(c, element, fragment)
+	}
+
+	if t.composedMarker && err == nil {
+		markComposed(fragment, response.source)
+	}
+	t.insert(element, fragment)
+	if err != nil {
+		c.fellBack(fragment)
+	}
+}
+
+// integrate queues the fragments nested in the content of the element and hoists its head elements.
+func (t *Templater) integrate(c *composition, element, fragment *html.Node) {
+	if nested := t.collect(fragment, c.depth+1); len(nested.fragments)+len(nested.dependents) > 0 {
+		c.nested = append(c.nested, nested)
+	}
+
+	for _, value := range t.Walk(fragment) {
+		switch value.Data {
+		case "link":
+			// fixme clean up this peace of sh*t
+			t.hoist(element, value)
+			value.Parent.RemoveChild(value)
+		case "meta":
+			if t.headConflicts != HeadConflictAppend {
+				t.hoist(element, value)
+				value.Parent.RemoveChild(value)
+			}
 		}
 	}
 }
 
 func (t *Templater) Resolve(node html.Node) (*html.Node, error) {
-	var attributeSource string
-	for _, value := range node.Attr {
-		if value.Key == "src" {
-			attributeSource = value.Val
+	return t.ResolveContext(context.Background(), node)
+}
+
+// ResolveContext resolves the fragment like Resolve, abandoning the request once the context is done.
+func (t *Templater) ResolveContext(ctx context.Context, node html.Node) (*html.Node, error) {
+	response, err := t.resolve(t.newComposition(ctx), node)
+	if err != nil {
+		return nil, err
+	}
+	return response.content, nil
+}
+
+// resolve requests the fragment within a span of its resolution, if tracing is enabled.
+func (t *Templater) resolve(c *composition, node html.Node) (*fragmentResponse, error) {
+	ctx, end := t.startSpan(c.ctx, node)
+	response, err := t.resolveWithin(ctx, c, node)
+	end(response, err)
+	return response, err
+}
+
+func (t *Templater) resolveWithin(ctx context.Context, c *composition, node html.Node) (*fragmentResponse, error) {
+	if t.gated(c, node) {
+		return nil, ErrorFlagDisabled
+	}
+
+	sources, err := t.sources(c, node)
+	if err != nil {
+		return nil, err
+	}
+
+	start := t.now()
+	timeout, err := t.timeoutFor(node)
+	if err != nil {
+		return nil, err
+	}
+	attemptTimeout := time.Duration(0)
+	total, err := totalTimeoutFor(node)
+	if err != nil {
+		return nil, err
+	}
+	if total > 0 {
+		timeout, attemptTimeout = total, timeout
+	}
+
+	group := attribute(node, groupAttribute)
+	if budget, ok := c.budget(group); ok {
+		if budget <= 0 {
+			return nil, ErrorBudgetExhausted
+		}
+		if timeout == 0 || budget < timeout {
+			timeout = budget
 		}
+
+		defer func() {
+			c.spend(group, t.since(start))
+		}()
 	}
 
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = t.withTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	var (
+		result *fragmentResponse
+		source string
+	)
+	for _, source = range sources {
+		result, err = t.attempt(ctx, c, node, source, attemptTimeout)
+		if err == nil || ctx.Err() != nil || errors.Is(err, ErrorClientRender) {
+			break
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := t.process(source, result.content); err != nil {
+		return nil, err
+	}
+
+	if t.preconnect {
+		c.connect(source, result.content)
+	}
+
+	if t.modulePreload {
+		c.collectModules(result.content)
+	}
+
+	if t.earlyHints {
+		c.hint(result.location, result.hints)
+	}
+
+	if t.linkHeaders {
+		c.hint(result.location, result.header.Values("Link"))
+	}
+
+	if elapsed := t.since(start); t.slo > 0 && elapsed > t.slo {
+		t.observe(Event{Kind: EventSLOViolation, Source: source, Duration: elapsed})
+	}
+
+	return result, nil
+}
+
+// sources returns the urls to request the fragment from, in the order they should be tried.
+func (t *Templater) sources(c *composition, node html.Node) ([]string, error) {
+	if name := attribute(node, upstreamAttribute); name != "" {
+		upstream, ok := t.upstreams[name]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrorUnknownUpstream, name)
+		}
+		replicas := upstream.replicas()
+		if len(replicas) == 0 {
+			return nil, fmt.Errorf("%w: %s", ErrorNoReplicas, name)
+		}
+		return replicas, nil
+	}
+
+	attributeSource := attribute(node, sourceAttribute)
 	if attributeSource == "" {
 		return nil, errors.New("no valid url found")
 	}
 
-	resp, err := t.client.Get(attributeSource)
+	evaluate := interpolateSource
+	if t.templateSrc {
+		evaluate = evaluateSource
+	}
+	source, err := evaluate(attributeSource, c.vars)
+	if err != nil {
+		return nil, err
+	}
+	return []string{source}, nil
+}
+
+func (t *Templater) request(ctx context.Context, c *composition, node html.Node, source string) (*fragmentResponse, error) {
+	if t.limiter != nil {
+		if err := t.wait(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, methodFor(node), source, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	c.forwardTo(req.Header)
+
+	userAgent := t.userAgent
+	if value, ok := lookupAttribute(node, userAgentAttribute); ok {
+		userAgent = value
+	}
+	if userAgent != "" {
+		req.Header.Set("User-Agent", userAgent)
+	}
+	if language := c.languageFor(node); language != "" {
+		req.Header.Set("Accept-Language", language)
+	}
+	if c.token != "" {
+		req.Header.Set(compositionTokenHeader, c.token)
+	}
+	c.condition(node, req.Header)
+	t.propagateDeadline(ctx, req.Header)
+
+	as := attribute(node, asAttribute)
+	switch as {
+	case asWebSocket:
+		return t.receive(ctx, source, req.Header)
+	case asSSE:
+		req.Header.Set("Accept", "text/event-stream")
+	case asJSONScript:
+		req.Header.Set("Accept", "application/json")
+	}
+	if t.headPrecheck && req.Method == http.MethodGet && attribute(node, useErrorBodyAttribute) != "true" {
+		if err := t.precheck(req); err != nil {
+			return nil, err
+		}
+	}
+
+	done := t.track()
+	result, err := t.coalesce(req, node)
+	done()
+	if err != nil {
+		return nil, err
+	}
+
+	identify(node, result)
+	return result, nil
+}
+
+// identify sets the id of a json-script fragment on the script element embedding its data.
+func identify(node html.Node, response *fragmentResponse) {
+	if id := attribute(node, idAttribute); attribute(node, asAttribute) == asJSONScript && id != "" {
+		setAttribute(response.content.FirstChild, idAttribute, id)
+	}
+}
+
+// fragmentResponse is the parsed response of a fragment request.
+type fragmentResponse struct {
+	content *html.Node
+	header  http.Header
+	source  string
+	// location is the url the response was finally received from, after following redirects.
+	location string
+	status   int
+	raw      []byte
+	hints    []string
+}
+
+// clone copies the response, so the copy can be spliced without mutating the original.
+func (r *fragmentResponse) clone() *fragmentResponse {
+	return &fragmentResponse{
+		content:  cloneNode(r.content),
+		header:   r.header.Clone(),
+		source:   r.source,
+		location: r.location,
+		status:   r.status,
+		raw:      r.raw,
+		hints:    r.hints,
+	}
+}
+
+// fetch requests the fragment and parses the response into the children of a new node.
+func (t *Templater) fetch(req *http.Request, node html.Node) (*fragmentResponse, error) {
+	as := attribute(node, asAttribute)
+	var hints func() []string
+	if t.earlyHints {
+		req, hints = traceEarlyHints(req)
+	}
+
+	resp, err := t.httpClient().Do(req)
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, errors.New("could not resolve the fragment")
+	if resp.StatusCode == http.StatusNotModified {
+		return nil, ErrorNotModified
+	}
+	if resp.StatusCode != http.StatusOK && attribute(node, useErrorBodyAttribute) != "true" {
+		return nil, StatusError{StatusCode: resp.StatusCode}
+	}
+	if t.rendersClient(resp.Header) {
+		return nil, ErrorClientRender
+	}
+	if err := t.checkContentType(resp.Header, as); err != nil {
+		return nil, err
+	}
+
+	var (
+		body io.Reader = newLimitedReader(resp.Body, t.responseLimit())
+		raw  []byte
+	)
+	if as == asSSE {
+		// The event stream stays open after its first event, so only that event is read.
+		body, err = firstEvent(t.stripLeading(body))
+		if err != nil {
+			return nil, err
+		}
+	}
+	if t.retainRaw {
+		raw, err = io.ReadAll(body)
+		if err != nil {
+			return nil, err
+		}
+		body = bytes.NewReader(raw)
+	}
+	if as != asSSE {
+		body = t.stripLeading(body)
+	}
+
+	parse := parseContent
+	if section := tableContext(node); section != "" {
+		parse = parseIn(section)
+	}
+	switch as {
+	case asJSONScript:
+		parse = embedJSON
+	case asTemplate:
+		parse = html.Parse
+	}
+
+	result, err := t.parseWithin(decoding(parse, resp.Header.Get("Content-Type")), body)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &fragmentResponse{content: result, header: resp.Header, source: req.URL.String(), location: resp.Request.URL.String(), status: resp.StatusCode, raw: raw}
+	if hints != nil {
+		response.hints = hints()
 	}
+	return response, nil
+}
+
+// parseContent parses fragment markup into the children of a new node.
+func parseContent(reader io.Reader) (*html.Node, error) {
+	return parseFragment(reader, &html.Node{Type: html.ElementNode, DataAtom: atom.Lookup([]byte(contentIdentifier)), Data: contentIdentifier})
+}
 
-	content, err := html.ParseFragment(resp.Body, &html.Node{Type: html.ElementNode, DataAtom: atom.Lookup([]byte(contentIdentifier)), Data: contentIdentifier})
+// parseFragment parses fragment markup in the context element into the children of a new node.
+func parseFragment(reader io.Reader, context *html.Node) (*html.Node, error) {
+	content, err := html.ParseFragment(reader, context)
 	if err != nil {
 		return nil, err
 	}
@@ -109,10 +702,71 @@ func (t *Templater) Resolve(node html.Node) (*html.Node, error) {
 	for _, value := range content {
 		result.AppendChild(value)
 	}
-
 	return result, nil
 }
 
+func (t *Templater) process(source string, content *html.Node) error {
+	t.qualifyAssets(source, content)
+	t.rewriteAssets(content)
+	if err := t.secureAssets(content); err != nil {
+		return err
+	}
+	t.hardenLinks(content)
+	t.addReturnParams(content)
+	t.transform(source, content)
+	if err := t.checkForeignFragments(source, content); err != nil {
+		return err
+	}
+
+	absolutizeFragments(source, t.tagName(), content)
+	if t.emailMode {
+		absolutizeStylesheets(source, content)
+	}
+	return nil
+}
+
+// tagName returns the tag name of fragment elements, fragment unless configured otherwise.
+func (t *Templater) tagName() string {
+	if t.fragmentTag == "" {
+		return fragmentIdentifier
+	}
+	return t.fragmentTag
+}
+
+// httpClient returns the client requesting the fragments, the default client for a zero templater.
+// A configured redirect policy applies to a copy of the client.
+func (t *Templater) httpClient() *http.Client {
+	client := t.client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	if !t.redirects {
+		return client
+	}
+
+	copied := *client
+	copied.CheckRedirect = t.checkRedirect
+	return &copied
+}
+
+func attribute(node html.Node, key string) string {
+	value, _ := lookupAttribute(node, key)
+	return value
+}
+
+func lookupAttribute(node html.Node, key string) (string, bool) {
+	var (
+		result string
+		found  bool
+	)
+	for _, value := range node.Attr {
+		if value.Key == key {
+			result, found = value.Val, true
+		}
+	}
+	return result, found
+}
+
 func (t *Templater) FindSection(data string, node *html.Node) (*html.Node, error) {
 	root := node
 	if node.Parent != nil {
@@ -136,6 +790,15 @@ func (t *Templater) AddHeader(root, element *html.Node) error {
 		return fmt.Errorf("could not find head section: %w", err)
 	}
 
+	if element.DataAtom == atom.Link {
+		key := linkKey(*element)
+		for child := head.FirstChild; child != nil; child = child.NextSibling {
+			if child.DataAtom == atom.Link && linkKey(*child) == key {
+				return nil
+			}
+		}
+	}
+
 	head.AppendChild(&html.Node{
 		FirstChild: element.FirstChild,
 		LastChild:  element.LastChild,
@@ -147,26 +810,58 @@ func (t *Templater) AddHeader(root, element *html.Node) error {
 	return nil
 }
 
-func (t *Templater) AddScript(node *html.Node) error {
-	return nil
+// linkKey identifies a link element by its relation and target.
+func linkKey(link html.Node) string {
+	return strings.ToLower(attribute(link, "rel")) + " " + attribute(link, "href")
 }
 
-func (t *Templater) Walk(node *html.Node) (result []*html.Node) {
-	var visit func(n *html.Node) []*html.Node
-	visit = func(n *html.Node) []*html.Node {
-		if n.FirstChild != nil {
-			return append(result, append(visit(n.FirstChild), n.FirstChild)...)
+// AddScript appends a copy of the script element to the head, or the body if the document has no head.
+// Scripts with a src already present in the document are not added again.
+func (t *Templater) AddScript(root, element *html.Node) error {
+	section, err := t.FindSection("head", root)
+	if err != nil {
+		if section, err = t.FindSection("body", root); err != nil {
+			return fmt.Errorf("could not find head or body section: %w", err)
 		}
+	}
 
-		if n.NextSibling != nil {
-			return append(result, append(visit(n.NextSibling), n.NextSibling)...)
-		}
+	if t.containsScript(section, element) {
+		return nil
+	}
 
-		if n.Parent != nil && n.Parent.NextSibling != nil {
-			return append(result, append(visit(n.Parent.NextSibling), n.Parent.NextSibling)...)
-		}
+	section.AppendChild(&html.Node{
+		FirstChild: element.FirstChild,
+		LastChild:  element.LastChild,
+		Type:       element.Type,
+		DataAtom:   element.DataAtom,
+		Data:       element.Data,
+		Attr:       element.Attr,
+	})
+	return nil
+}
 
-		return nil
+// Walk collects the nodes of All into a slice.
+func (t *Templater) Walk(node *html.Node) []*html.Node {
+	return slices.Collect(t.All(node))
+}
+
+// All iterates the descendants of the node depth first in document order, visiting each exactly
+// once. Ranging over it allocates no slice and may stop early.
+func (t *Templater) All(node *html.Node) iter.Seq[*html.Node] {
+	return func(yield func(*html.Node) bool) {
+		walk(node, yield)
+	}
+}
+
+// walk yields each child of the node followed by its own descendants. The next sibling is taken
+// before yielding, so a yielded node may be detached without ending the traversal.
+func walk(node *html.Node, yield func(*html.Node) bool) bool {
+	for child := node.FirstChild; child != nil; {
+		next := child.NextSibling
+		if !yield(child) || !walk(child, yield) {
+			return false
+		}
+		child = next
 	}
-	return visit(node)
+	return true
 }
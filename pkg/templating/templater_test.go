@@ -68,6 +68,7 @@ func TestTemplater_Parse(t *testing.T) {
 
 func TestTemplater_ParseWithNode_FallBack(t *testing.T) {
 	brokenDummy := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("Content-Type", "text/html")
 		writer.WriteHeader(http.StatusNotFound)
 	}))
 
@@ -87,6 +88,7 @@ func TestTemplater_ParseWithNode_FallBack(t *testing.T) {
 		const expected = "<html><head></head><body><><content><p>hello</p><>from</><a>the other site</a></content></></body></html>"
 
 		dummy := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+			writer.Header().Set("Content-Type", "text/html")
 			writer.Write([]byte(fmt.Sprintf(`<content><p>hello</p><fragment src="%s">from</fragment><a>the other site</a></content>`, brokenDummy.URL)))
 		}))
 
@@ -104,6 +106,7 @@ func TestTemplater_ParseWithNode_FallBack(t *testing.T) {
 		const expected = "<html><head></head><body><><content><p>hello</p><>from</><>the other site</></content></></body></html>"
 
 		dummy := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+			writer.Header().Set("Content-Type", "text/html")
 			writer.Write([]byte(fmt.Sprintf(`<content><p>hello</p><fragment src="%s">from</fragment><fragment src="%s">the other site</fragment></content>`, brokenDummy.URL, brokenDummy.URL)))
 		}))
 
@@ -121,10 +124,12 @@ func TestTemplater_ParseWithNode_FallBack(t *testing.T) {
 		const expected = "<html><head></head><body><><content><p>hello</p><>from</><><content>the other site</content></></content></></body></html>"
 
 		anotherDummy := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+			writer.Header().Set("Content-Type", "text/html")
 			writer.Write([]byte(`<content>the other site</content>`))
 		}))
 
 		dummy := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+			writer.Header().Set("Content-Type", "text/html")
 			writer.Write([]byte(fmt.Sprintf(`<content><p>hello</p><fragment src="%s">from</fragment><fragment src="%s"></fragment></content>`, brokenDummy.URL, anotherDummy.URL)))
 		}))
 
@@ -146,19 +151,31 @@ func TestTemplater_Walk(t *testing.T) {
 	}{
 		{
 			input:    strings.NewReader(""),
-			expected: "body head html ",
+			expected: "html head body ",
 		},
 		{
 			input:    strings.NewReader("<html></html>"),
-			expected: "body head html ",
+			expected: "html head body ",
 		},
 		{
 			input:    strings.NewReader(`<html><head/><body><p><a/><a/></p><div><a/></div><div><foo/><bar/></body></html>`),
-			expected: "bar foo a div a div a a p body head html ",
+			expected: "html head body p a a div a div a foo bar ",
 		},
 		{
 			input:    strings.NewReader(`<html><head/><body><div/><div/></body></html>`),
-			expected: "div div body head html ",
+			expected: "html head body div div ",
+		},
+		{
+			input:    strings.NewReader(`<html><head><title>x</title></head><body><p><b>x</b></p><div><i>x</i></div></body></html>`),
+			expected: "html head title x body p b x div i x ",
+		},
+		{
+			input:    strings.NewReader(`<html><body><section><article><div><span><em>x</em></span></div></article></section><footer/></body></html>`),
+			expected: "html head body section article div span em x footer ",
+		},
+		{
+			input:    strings.NewReader(`<html><body><ul><li>1</li><li>2</li><li>3</li></ul><ol><li/><li/></ol><p/></body></html>`),
+			expected: "html head body ul li 1 li 2 li 3 ol li li p ",
 		},
 	}
 
@@ -176,8 +193,23 @@ func TestTemplater_Walk(t *testing.T) {
 	}
 }
 
+func TestTemplater_All(t *testing.T) {
+	var templater Templater
+	content, _ := html.Parse(strings.NewReader(`<html><head/><body><p><a/><a/></p><div><a/></div><div><foo/><bar/></body></html>`))
+
+	var actual string
+	for value := range templater.All(content) {
+		if value.Data == "div" {
+			break
+		}
+		actual += fmt.Sprintf("%s ", value.Data)
+	}
+	assert.Equal(t, "html head body p a a ", actual)
+}
+
 func TestTemplater_Resolve(t *testing.T) {
 	dummy := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("Content-Type", "text/html")
 		writer.Write([]byte(fmt.Sprintf("<html><head/><body><%v>Foo</%v></body></html>", contentIdentifier, contentIdentifier)))
 	}))
 
@@ -197,7 +229,7 @@ func TestTemplater_Resolve(t *testing.T) {
 		{
 			fragment: html.Node{
 				Data: fragmentIdentifier,
-				Attr: []html.Attribute{{"", "src", dummy.URL}},
+				Attr: []html.Attribute{{Key: "src", Val: dummy.URL}},
 			},
 			expected: "<><content>Foo</content></>",
 		},
@@ -221,6 +253,7 @@ func TestTemplater_Resolve(t *testing.T) {
 
 func TestTemplater_Resolve_Fallback(t *testing.T) {
 	dummy := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("Content-Type", "text/html")
 		writer.WriteHeader(http.StatusInternalServerError)
 	}))
 
@@ -232,7 +265,7 @@ func TestTemplater_Resolve_Fallback(t *testing.T) {
 		{
 			fragment: html.Node{
 				Data: fragmentIdentifier,
-				Attr: []html.Attribute{{"", "src", dummy.URL}},
+				Attr: []html.Attribute{{Key: "src", Val: dummy.URL}},
 			},
 			expectedError: true,
 		},
@@ -254,6 +287,44 @@ func TestTemplater_Resolve_Fallback(t *testing.T) {
 	}
 }
 
+func TestTemplater_Resolve_UserAgent(t *testing.T) {
+	userAgents := make(chan string, 1)
+	dummy := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("Content-Type", "text/html")
+		userAgents <- request.UserAgent()
+		writer.Write([]byte("<content>Foo</content>"))
+	}))
+
+	tt := []struct {
+		fragment html.Node
+		expected string
+	}{
+		{
+			fragment: html.Node{
+				Data: fragmentIdentifier,
+				Attr: []html.Attribute{{Key: "src", Val: dummy.URL}},
+			},
+			expected: "duc-duc-go",
+		},
+		{
+			fragment: html.Node{
+				Data: fragmentIdentifier,
+				Attr: []html.Attribute{{Key: "src", Val: dummy.URL}, {Key: "user-agent", Val: "special-agent"}},
+			},
+			expected: "special-agent",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run("", func(t *testing.T) {
+			templater := New(WithUserAgent("duc-duc-go"))
+			_, err := templater.Resolve(tc.fragment)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, <-userAgents)
+		})
+	}
+}
+
 func TestTemplater_FindSection(t *testing.T) {
 	root, _ := html.Parse(strings.NewReader("<html><head/><body><a>Foo</a></body></html>"))
 
@@ -267,9 +338,10 @@ func TestTemplater_FindSection(t *testing.T) {
 
 func TestTemplater_ParseWithNode_Head(t *testing.T) {
 	t.SkipNow()
-	
+
 	const expected = ""
 	dummy := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("Content-Type", "text/html")
 		writer.Write([]byte(`<content><link id="styles" type="text/css" media="all" rel="stylesheet" href="https://example.com"></content>`))
 	}))
 
@@ -0,0 +1,71 @@
+package templating
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"golang.org/x/net/html"
+)
+
+const (
+	criticalityAttribute  = "criticality"
+	timeoutAttribute      = "timeout"
+	totalTimeoutAttribute = "total-timeout"
+)
+
+var (
+	ErrorInvalidTimeout = errors.New("invalid timeout")
+)
+
+// defaultCriticality maps the criticality of a fragment to the multiplier of its timeout.
+var defaultCriticality = map[string]float64{
+	"high":   2,
+	"medium": 1,
+	"low":    0.5,
+}
+
+// timeoutFor returns the time the fragment may take. The timeout attribute of the fragment takes
+// precedence over the policy of its host and the default, which are weighted by its criticality.
+func (t *Templater) timeoutFor(node html.Node) (time.Duration, error) {
+	if timeout, err := durationAttribute(node, timeoutAttribute); err != nil || timeout > 0 {
+		return timeout, err
+	}
+
+	timeout := t.timeout
+	if policy, ok := t.policyFor(node); ok && policy.Timeout > 0 {
+		timeout = policy.Timeout
+	}
+	if timeout <= 0 {
+		return timeout, nil
+	}
+
+	level := attribute(node, criticalityAttribute)
+	multiplier, ok := t.criticality[level]
+	if !ok {
+		multiplier, ok = defaultCriticality[level]
+	}
+	if !ok {
+		return timeout, nil
+	}
+	return time.Duration(float64(timeout) * multiplier), nil
+}
+
+// totalTimeoutFor returns the time the fragment may take across all attempts, zero if unlimited.
+func totalTimeoutFor(node html.Node) (time.Duration, error) {
+	return durationAttribute(node, totalTimeoutAttribute)
+}
+
+// durationAttribute parses the positive duration of the attribute, zero if it is absent.
+func durationAttribute(node html.Node, key string) (time.Duration, error) {
+	value := attribute(node, key)
+	if value == "" {
+		return 0, nil
+	}
+
+	duration, err := time.ParseDuration(value)
+	if err != nil || duration <= 0 {
+		return 0, fmt.Errorf("%w: %s=%q", ErrorInvalidTimeout, key, value)
+	}
+	return duration, nil
+}
@@ -0,0 +1,159 @@
+package templating
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/html"
+)
+
+func TestTemplater_timeoutFor(t *testing.T) {
+	tt := []struct {
+		options     []Option
+		criticality string
+		expected    time.Duration
+	}{
+		{options: []Option{WithTimeout(time.Second)}, criticality: "", expected: time.Second},
+		{options: []Option{WithTimeout(time.Second)}, criticality: "high", expected: 2 * time.Second},
+		{options: []Option{WithTimeout(time.Second)}, criticality: "medium", expected: time.Second},
+		{options: []Option{WithTimeout(time.Second)}, criticality: "low", expected: 500 * time.Millisecond},
+		{options: []Option{WithTimeout(time.Second)}, criticality: "unknown", expected: time.Second},
+		{options: []Option{WithTimeout(time.Second), WithCriticality("low", 0.25)}, criticality: "low", expected: 250 * time.Millisecond},
+		{options: []Option{WithCriticality("high", 2)}, criticality: "high", expected: 0},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.criticality, func(t *testing.T) {
+			templater := New(tc.options...)
+			actual, err := templater.timeoutFor(html.Node{Attr: []html.Attribute{{Key: "criticality", Val: tc.criticality}}})
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+}
+
+func TestTemplater_Parse_Criticality(t *testing.T) {
+	dummy := slowDummy(150*time.Millisecond, "<content>Foo</content>")
+	defer dummy.Close()
+
+	const expected = "<html><head></head><body><><content>Foo</content></><>Low</></body></html>"
+
+	templater := New(WithTimeout(100 * time.Millisecond))
+	actual, err := templater.Parse(strings.NewReader(fmt.Sprintf(`<html><body><fragment criticality="high" src="%[1]s">High</fragment><fragment criticality="low" src="%[1]s">Low</fragment></body></html>`, dummy.URL)))
+	assert.NoError(t, err)
+	assert.Equal(t, expected, actual)
+}
+
+func TestTemplater_Parse_TotalTimeout(t *testing.T) {
+	tt := map[string]struct {
+		total    string
+		expected string
+		requests int32
+	}{
+		"budget allows a second attempt": {
+			total:    "1s",
+			expected: "<html><head></head><body><><content>Foo</content></></body></html>",
+			requests: 2,
+		},
+		"budget too small for a second attempt": {
+			total:    "40ms",
+			expected: "<html><head></head><body><>Bar</></body></html>",
+			requests: 1,
+		},
+		"invalid budget": {
+			total:    "soon",
+			expected: "<html><head></head><body><>Bar</></body></html>",
+			requests: 0,
+		},
+	}
+
+	for name, tc := range tt {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			var requests int32
+			dummy := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+				writer.Header().Set("Content-Type", "text/html")
+				if atomic.AddInt32(&requests, 1) == 1 {
+					select {
+					case <-time.After(time.Second):
+					case <-request.Context().Done():
+					}
+					return
+				}
+				writer.Write([]byte("<content>Foo</content>"))
+			}))
+			defer dummy.Close()
+
+			templater := New(WithTimeout(50*time.Millisecond), WithUpstream("api", []string{dummy.URL, dummy.URL}, StrategyRoundRobin))
+			actual, err := templater.Parse(strings.NewReader(fmt.Sprintf(`<html><body><fragment upstream="api" total-timeout="%s">Bar</fragment></body></html>`, tc.total)))
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, actual)
+			assert.Equal(t, tc.requests, atomic.LoadInt32(&requests))
+		})
+	}
+}
+
+func TestTemplater_Parse_TimeoutAttribute(t *testing.T) {
+	dummy := slowDummy(100*time.Millisecond, "<content>Foo</content>")
+	defer dummy.Close()
+
+	tt := map[string]struct {
+		timeout  string
+		expected string
+	}{
+		"shorter than the response": {
+			timeout:  "20ms",
+			expected: "<html><head></head><body><>Bar</></body></html>",
+		},
+		"overrides the default": {
+			timeout:  "1s",
+			expected: "<html><head></head><body><><content>Foo</content></></body></html>",
+		},
+		"invalid duration": {
+			timeout:  "soon",
+			expected: "<html><head></head><body><>Bar</></body></html>",
+		},
+	}
+
+	for name, tc := range tt {
+		tc := tc
+		t.Run(name, func(t *testing.T) {
+			templater := New(WithTimeout(20*time.Millisecond), WithCriticality("high", 100))
+			actual, err := templater.Parse(strings.NewReader(fmt.Sprintf(`<html><body><fragment criticality="high" timeout="%s" src="%s">Bar</fragment></body></html>`, tc.timeout, dummy.URL)))
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+}
+
+func TestTemplater_Resolve_InvalidTimeout(t *testing.T) {
+	var templater Templater
+	_, err := templater.Resolve(html.Node{Attr: []html.Attribute{{Key: "src", Val: "http://example.com"}, {Key: "timeout", Val: "500"}}})
+	assert.ErrorIs(t, err, ErrorInvalidTimeout)
+}
+
+func TestTemplater_ParseWithResult_InvalidTimeout(t *testing.T) {
+	var requests int32
+	dummy := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("Content-Type", "text/html")
+		atomic.AddInt32(&requests, 1)
+		writer.Write([]byte("<content>Foo</content>"))
+	}))
+	defer dummy.Close()
+
+	templater := New()
+	actual, failures, err := templater.ParseWithResult(strings.NewReader(fmt.Sprintf(`<html><body><fragment id="nav" timeout="soon" src="%s">Bar</fragment></body></html>`, dummy.URL)))
+	assert.NoError(t, err)
+	assert.Equal(t, "<html><head></head><body><>Bar</></body></html>", actual)
+	if assert.Len(t, failures, 1) {
+		assert.Equal(t, "nav", failures[0].ID)
+		assert.ErrorIs(t, failures[0].Err, ErrorInvalidTimeout)
+	}
+	assert.Equal(t, int32(0), atomic.LoadInt32(&requests))
+}
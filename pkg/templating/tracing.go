@@ -0,0 +1,63 @@
+package templating
+
+import (
+	"context"
+	"errors"
+
+	"go.opentelemetry.io/otel"
+	attr "go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/net/html"
+)
+
+const tracerName = "github.com/Am3o/duc-duc-go/pkg/templating"
+
+// spanKey is the context key of the span of the fragment resolution.
+type spanKey struct{}
+
+// startSpan starts a span for the resolution of the fragment as child of the span of the context.
+// The returned function ends it with the outcome of the resolution. Without a tracer configured
+// the context is returned as is.
+func (t *Templater) startSpan(ctx context.Context, node html.Node) (context.Context, func(*fragmentResponse, error)) {
+	if t.tracer == nil {
+		return ctx, func(*fragmentResponse, error) {}
+	}
+
+	ctx, span := t.tracer.Start(ctx, "fragment.resolve", trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(
+		attr.String("fragment.src", attribute(node, sourceAttribute)),
+		attr.String("fragment.id", attribute(node, idAttribute)),
+	))
+	ctx = context.WithValue(ctx, spanKey{}, span)
+	return ctx, func(response *fragmentResponse, err error) {
+		defer span.End()
+
+		fallback := err != nil && !errors.Is(err, ErrorNotModified) && !errors.Is(err, ErrorClientRender)
+		span.SetAttributes(attr.Bool("fragment.fallback", fallback))
+
+		var statusError StatusError
+		switch {
+		case err == nil:
+			span.SetAttributes(attr.Int("http.response.status_code", response.status))
+		case errors.As(err, &statusError):
+			span.SetAttributes(attr.Int("http.response.status_code", statusError.StatusCode))
+		}
+		if fallback {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+	}
+}
+
+// traceCache records on the span of the fragment resolution whether the fragment was served from the
+// cache. Spans of the caller are left untouched, so nothing is recorded without a tracer configured.
+func traceCache(ctx context.Context, hit bool) {
+	if span, ok := ctx.Value(spanKey{}).(trace.Span); ok {
+		span.SetAttributes(attr.Bool("fragment.cache_hit", hit))
+	}
+}
+
+// globalTracer returns the tracer of the globally registered tracer provider.
+func globalTracer() trace.Tracer {
+	return otel.Tracer(tracerName)
+}
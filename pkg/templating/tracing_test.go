@@ -0,0 +1,94 @@
+package templating
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	attr "go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestTemplater_Parse_Tracer(t *testing.T) {
+	dummy := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("Content-Type", "text/html")
+		if request.URL.Path == "/broken" {
+			writer.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		writer.Write([]byte("<content>Foo</content>"))
+	}))
+	defer dummy.Close()
+
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	templater := New(WithTracer(provider.Tracer("test")), WithCache(time.Minute))
+
+	document := fmt.Sprintf(`<html><body><fragment id="nav" src="%[1]s/nav"></fragment><fragment id="footer" src="%[1]s/broken">Footer</fragment></body></html>`, dummy.URL)
+	for i := 0; i < 2; i++ {
+		_, err := templater.Parse(strings.NewReader(document))
+		assert.NoError(t, err)
+	}
+
+	spans := recorder.Ended()
+	if !assert.Len(t, spans, 4) {
+		return
+	}
+
+	expected := []struct {
+		id       string
+		status   int64
+		fallback bool
+		cacheHit bool
+	}{
+		{id: "nav", status: http.StatusOK},
+		{id: "footer", status: http.StatusInternalServerError, fallback: true},
+		{id: "nav", status: http.StatusOK, cacheHit: true},
+		{id: "footer", status: http.StatusInternalServerError, fallback: true},
+	}
+	for i, span := range spans {
+		attributes := make(map[attr.Key]attr.Value)
+		for _, value := range span.Attributes() {
+			attributes[value.Key] = value.Value
+		}
+
+		assert.Equal(t, "fragment.resolve", span.Name())
+		assert.Equal(t, expected[i].id, attributes["fragment.id"].AsString())
+		assert.Contains(t, attributes["fragment.src"].AsString(), dummy.URL)
+		assert.Equal(t, expected[i].status, attributes["http.response.status_code"].AsInt64())
+		assert.Equal(t, expected[i].fallback, attributes["fragment.fallback"].AsBool())
+		assert.Equal(t, expected[i].cacheHit, attributes["fragment.cache_hit"].AsBool())
+		if expected[i].fallback {
+			assert.Equal(t, codes.Error, span.Status().Code)
+		}
+	}
+}
+
+func TestTemplater_ParseContext_WithoutTracer(t *testing.T) {
+	dummy := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("Content-Type", "text/html")
+		writer.Write([]byte("<content>Foo</content>"))
+	}))
+	defer dummy.Close()
+
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	ctx, span := provider.Tracer("test").Start(context.Background(), "incoming")
+
+	templater := New(WithCache(time.Minute))
+	_, err := templater.ParseContext(ctx, strings.NewReader(fmt.Sprintf(`<html><body><fragment src="%s"></fragment></body></html>`, dummy.URL)))
+	assert.NoError(t, err)
+	span.End()
+
+	spans := recorder.Ended()
+	if assert.Len(t, spans, 1) {
+		assert.Empty(t, spans[0].Attributes())
+	}
+}
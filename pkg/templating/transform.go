@@ -0,0 +1,43 @@
+package templating
+
+import (
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// TransformFunc post-processes the content of a resolved fragment before it is spliced.
+type TransformFunc func(content *html.Node)
+
+type patternTransform struct {
+	pattern   *regexp.Regexp
+	transform TransformFunc
+}
+
+// globPattern compiles a glob, where * matches any sequence and ? any single character, into a regular expression.
+func globPattern(pattern string) *regexp.Regexp {
+	var builder strings.Builder
+	builder.WriteString("^")
+	for _, r := range pattern {
+		switch r {
+		case '*':
+			builder.WriteString(".*")
+		case '?':
+			builder.WriteString(".")
+		default:
+			builder.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	builder.WriteString("$")
+
+	return regexp.MustCompile(builder.String())
+}
+
+func (t *Templater) transform(source string, content *html.Node) {
+	for _, transform := range t.transforms {
+		if transform.pattern.MatchString(source) {
+			transform.transform(content)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package templating
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/html"
+)
+
+func TestGlobPattern(t *testing.T) {
+	tt := []struct {
+		pattern  string
+		input    string
+		expected bool
+	}{
+		{pattern: "*.example.com/*", input: "https://nav.example.com/widget", expected: true},
+		{pattern: "*.example.com/*", input: "https://example.org/widget", expected: false},
+		{pattern: "*.example.com/*", input: "https://nav.exampleXcom/widget", expected: false},
+		{pattern: "https://api.example.com/v?/*", input: "https://api.example.com/v2/user", expected: true},
+		{pattern: "https://api.example.com/v?/*", input: "https://api.example.com/v10/user", expected: false},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.pattern, func(t *testing.T) {
+			assert.Equal(t, tc.expected, globPattern(tc.pattern).MatchString(tc.input))
+		})
+	}
+}
+
+func TestTemplater_Parse_TransformForPattern(t *testing.T) {
+	dummy := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("Content-Type", "text/html")
+		writer.Write([]byte("<content>Foo</content>"))
+	}))
+	defer dummy.Close()
+
+	annotate := func(value string) TransformFunc {
+		return func(content *html.Node) {
+			content.FirstChild.Attr = append(content.FirstChild.Attr, html.Attribute{Key: "data-" + value, Val: value})
+		}
+	}
+
+	templater := New(
+		WithTransformForPattern("*/nav/*", annotate("nav")),
+		WithTransformForPattern("http://*", annotate("http")),
+	)
+
+	const expected = `<html><head></head><body><><content data-nav="nav" data-http="http">Foo</content></><><content data-http="http">Foo</content></></body></html>`
+	actual, err := templater.Parse(strings.NewReader(fmt.Sprintf(`<html><body><fragment src="%[1]s/nav/menu"></fragment><fragment src="%[1]s/footer"></fragment></body></html>`, dummy.URL)))
+	assert.NoError(t, err)
+	assert.Equal(t, expected, actual)
+}
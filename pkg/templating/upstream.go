@@ -0,0 +1,50 @@
+package templating
+
+import (
+	"errors"
+	"math/rand"
+	"sync/atomic"
+)
+
+type Strategy int
+
+const (
+	// StrategyRoundRobin starts every fragment request at the next replica in turn.
+	StrategyRoundRobin Strategy = iota
+	// StrategyRandom starts every fragment request at a randomly chosen replica.
+	StrategyRandom
+)
+
+var (
+	ErrorUnknownUpstream = errors.New("unknown upstream")
+	ErrorNoReplicas      = errors.New("upstream has no replicas")
+)
+
+// upstream balances the fragment requests of a named upstream over its replicas.
+type upstream struct {
+	urls     []string
+	strategy Strategy
+	next     uint32
+}
+
+// replicas returns all replica urls, starting with the one chosen by the strategy
+// and followed by the others to fail over to.
+func (u *upstream) replicas() []string {
+	if len(u.urls) == 0 {
+		return nil
+	}
+
+	var first int
+	switch u.strategy {
+	case StrategyRandom:
+		first = rand.Intn(len(u.urls))
+	default:
+		first = int((atomic.AddUint32(&u.next, 1) - 1) % uint32(len(u.urls)))
+	}
+
+	result := make([]string, 0, len(u.urls))
+	for i := range u.urls {
+		result = append(result, u.urls[(first+i)%len(u.urls)])
+	}
+	return result
+}
@@ -0,0 +1,89 @@
+package templating
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/html"
+)
+
+func TestTemplater_Resolve_Upstream(t *testing.T) {
+	var requests [3]int32
+	replicas := make([]string, len(requests))
+	for i := range replicas {
+		i := i
+		replica := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+			writer.Header().Set("Content-Type", "text/html")
+			atomic.AddInt32(&requests[i], 1)
+			writer.Write([]byte(fmt.Sprintf("<content>Replica %d</content>", i)))
+		}))
+		defer replica.Close()
+		replicas[i] = replica.URL
+	}
+
+	fragment := html.Node{
+		Data: fragmentIdentifier,
+		Attr: []html.Attribute{{Key: "upstream", Val: "nav"}},
+	}
+
+	t.Run("should distribute the requests over the replicas", func(t *testing.T) {
+		templater := New(WithUpstream("nav", replicas, StrategyRoundRobin))
+		for i := 0; i < 6; i++ {
+			resolved, err := templater.Resolve(fragment)
+			assert.NoError(t, err)
+
+			var actual bytes.Buffer
+			html.Render(&actual, resolved)
+			assert.Equal(t, fmt.Sprintf("<><content>Replica %d</content></>", i%3), actual.String())
+		}
+		assert.Equal(t, [3]int32{2, 2, 2}, requests)
+	})
+
+	t.Run("should fail over to the next replica", func(t *testing.T) {
+		dead := httptest.NewServer(http.NotFoundHandler())
+		dead.Close()
+
+		templater := New(WithUpstream("nav", []string{dead.URL, replicas[0]}, StrategyRandom))
+		for i := 0; i < 4; i++ {
+			resolved, err := templater.Resolve(fragment)
+			assert.NoError(t, err)
+
+			var actual bytes.Buffer
+			html.Render(&actual, resolved)
+			assert.Equal(t, "<><content>Replica 0</content></>", actual.String())
+		}
+	})
+
+	t.Run("should reject an unknown upstream", func(t *testing.T) {
+		templater := New()
+		_, err := templater.Resolve(fragment)
+		assert.True(t, errors.Is(err, ErrorUnknownUpstream))
+	})
+
+	t.Run("should fall back for an upstream without replicas", func(t *testing.T) {
+		templater := New(WithUpstream("nav", nil, StrategyRoundRobin))
+		actual, err := templater.Parse(strings.NewReader(`<html><head></head><body><fragment upstream="nav"><div>Bar</div></fragment></body></html>`))
+		assert.NoError(t, err)
+		assert.Equal(t, "<html><head></head><body><><div>Bar</div></></body></html>", actual)
+	})
+
+	t.Run("should not be affected by changes to the given urls", func(t *testing.T) {
+		urls := []string{replicas[0]}
+		templater := New(WithUpstream("nav", urls, StrategyRoundRobin))
+		urls[0] = "http://127.0.0.1:0"
+
+		resolved, err := templater.Resolve(fragment)
+		assert.NoError(t, err)
+
+		var actual bytes.Buffer
+		html.Render(&actual, resolved)
+		assert.Equal(t, "<><content>Replica 0</content></>", actual.String())
+	})
+}
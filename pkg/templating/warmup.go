@@ -0,0 +1,71 @@
+package templating
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultWarmupTimeout = 5 * time.Second
+)
+
+// Warmup establishes connections to the hosts in the connection pool of the client ahead of the
+// first composition, so fragments skip the dns lookup, connect and tls handshake. Hosts are origins
+// like https://example.com, a bare host name uses https. All hosts are warmed up concurrently within
+// the warmup timeout, and the first failure is returned.
+func (t *Templater) Warmup(hosts []string) error {
+	timeout := t.warmupTimeout
+	if timeout <= 0 {
+		timeout = defaultWarmupTimeout
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	errs := make([]error, len(hosts))
+	var wg sync.WaitGroup
+	for i, host := range hosts {
+		i, host := i, host
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = t.warm(ctx, host)
+		}()
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			return fmt.Errorf("could not warm up %s: %w", hosts[i], err)
+		}
+	}
+	return nil
+}
+
+// warm sends a HEAD request to the origin, releasing its connection into the pool.
+func (t *Templater) warm(ctx context.Context, host string) error {
+	origin := host
+	if !strings.Contains(origin, "://") {
+		origin = "https://" + origin
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, origin, nil)
+	if err != nil {
+		return err
+	}
+	if t.userAgent != "" {
+		req.Header.Set("User-Agent", t.userAgent)
+	}
+
+	resp, err := t.httpClient().Do(req)
+	if err != nil {
+		return err
+	}
+	io.Copy(io.Discard, resp.Body)
+	return resp.Body.Close()
+}
@@ -0,0 +1,53 @@
+package templating
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTemplater_Warmup(t *testing.T) {
+	var connections, requests int32
+	dummy := httptest.NewUnstartedServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("Content-Type", "text/html")
+		atomic.AddInt32(&requests, 1)
+		writer.Write([]byte("<content>hello</content>"))
+	}))
+	dummy.Config.ConnState = func(conn net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			atomic.AddInt32(&connections, 1)
+		}
+	}
+	dummy.Start()
+	defer dummy.Close()
+
+	templater := New(WithClient(&http.Client{Transport: &http.Transport{}}))
+	assert.NoError(t, templater.Warmup([]string{dummy.URL}))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&connections))
+
+	actual, err := templater.Parse(strings.NewReader(fmt.Sprintf(`<html><body><fragment src="%s/teaser"></fragment></body></html>`, dummy.URL)))
+	assert.NoError(t, err)
+	assert.Equal(t, "<html><head></head><body><><content>hello</content></></body></html>", actual)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&requests))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&connections))
+}
+
+func TestTemplater_Warmup_Timeout(t *testing.T) {
+	dummy := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("Content-Type", "text/html")
+		time.Sleep(200 * time.Millisecond)
+	}))
+	defer dummy.Close()
+
+	templater := New(WithWarmupTimeout(50 * time.Millisecond))
+	start := time.Now()
+	assert.Error(t, templater.Warmup([]string{dummy.URL}))
+	assert.Less(t, int64(time.Since(start)), int64(200*time.Millisecond))
+}
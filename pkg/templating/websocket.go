@@ -0,0 +1,113 @@
+package templating
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/websocket"
+)
+
+const (
+	asWebSocket = "ws"
+)
+
+var (
+	ErrorWebSocketDisabled = errors.New("websocket fragments are not enabled")
+)
+
+// receive connects to a websocket endpoint and resolves the fragment from its first message,
+// which is limited to the maximum size of a response like the body of an http response.
+func (t *Templater) receive(ctx context.Context, source string, header http.Header) (*fragmentResponse, error) {
+	if !t.webSockets {
+		return nil, ErrorWebSocketDisabled
+	}
+
+	location, err := url.Parse(source)
+	if err != nil {
+		return nil, err
+	}
+
+	origin := url.URL{Scheme: "http", Host: location.Host}
+	if location.Scheme == "wss" {
+		origin.Scheme = "https"
+	}
+
+	config, err := websocket.NewConfig(source, origin.String())
+	if err != nil {
+		return nil, err
+	}
+	config.Header = header
+
+	conn, err := t.dialWebSocket(ctx, location)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-done:
+		}
+	}()
+
+	ws, err := websocket.NewClient(config, conn)
+	if err != nil {
+		return nil, err
+	}
+	defer ws.Close()
+	ws.MaxPayloadBytes = int(t.responseLimit())
+
+	var message string
+	if err := websocket.Message.Receive(ws, &message); err != nil {
+		if errors.Is(err, websocket.ErrFrameTooLarge) {
+			return nil, ErrorResponseTooLarge
+		}
+		return nil, err
+	}
+
+	content, err := parseContent(t.stripLeading(strings.NewReader(message)))
+	if err != nil {
+		return nil, err
+	}
+
+	response := &fragmentResponse{content: content, header: http.Header{}, source: source, status: http.StatusSwitchingProtocols}
+	if t.retainRaw {
+		response.raw = []byte(message)
+	}
+	return response, nil
+}
+
+func (t *Templater) dialWebSocket(ctx context.Context, location *url.URL) (net.Conn, error) {
+	host, port := location.Hostname(), location.Port()
+	switch location.Scheme {
+	case "ws":
+		if port == "" {
+			port = "80"
+		}
+
+		var dialer net.Dialer
+		return dialer.DialContext(ctx, "tcp", overrideAddress(t.hostOverrides, net.JoinHostPort(host, port)))
+	case "wss":
+		if port == "" {
+			port = "443"
+		}
+
+		dialer := tls.Dialer{Config: &tls.Config{ServerName: host}}
+		return dialer.DialContext(ctx, "tcp", overrideAddress(t.hostOverrides, net.JoinHostPort(host, port)))
+	default:
+		return nil, websocket.ErrBadScheme
+	}
+}
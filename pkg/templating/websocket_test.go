@@ -0,0 +1,62 @@
+package templating
+
+import (
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/html"
+	"golang.org/x/net/websocket"
+)
+
+func TestTemplater_Parse_WebSocket(t *testing.T) {
+	closed := make(chan struct{}, 1)
+	dummy := httptest.NewServer(websocket.Handler(func(ws *websocket.Conn) {
+		websocket.Message.Send(ws, "<p>live</p>")
+
+		var reply string
+		websocket.Message.Receive(ws, &reply)
+		closed <- struct{}{}
+	}))
+	defer dummy.Close()
+
+	document := fmt.Sprintf(`<html><body><fragment as="ws" src="ws%s">Offline</fragment></body></html>`, strings.TrimPrefix(dummy.URL, "http"))
+
+	t.Run("should splice the first message", func(t *testing.T) {
+		templater := New(WithWebSocketFragments(), WithTimeout(time.Second))
+		actual, err := templater.Parse(strings.NewReader(document))
+		assert.NoError(t, err)
+		assert.Equal(t, "<html><head></head><body><><p>live</p></></body></html>", actual)
+
+		select {
+		case <-closed:
+		case <-time.After(time.Second):
+			assert.Fail(t, "the websocket was not closed after the first message")
+		}
+	})
+
+	t.Run("should fall back when websocket fragments are disabled", func(t *testing.T) {
+		templater := New()
+		actual, err := templater.Parse(strings.NewReader(document))
+		assert.NoError(t, err)
+		assert.Equal(t, "<html><head></head><body><>Offline</></body></html>", actual)
+	})
+
+	t.Run("should fail for a message exceeding the maximum size", func(t *testing.T) {
+		templater := New(WithWebSocketFragments(), WithTimeout(time.Second), WithMaxResponseBytes(5))
+		_, err := templater.Resolve(html.Node{
+			Data: fragmentIdentifier,
+			Attr: []html.Attribute{{Key: "as", Val: "ws"}, {Key: "src", Val: "ws" + strings.TrimPrefix(dummy.URL, "http")}},
+		})
+		assert.ErrorIs(t, err, ErrorResponseTooLarge)
+
+		select {
+		case <-closed:
+		case <-time.After(time.Second):
+			assert.Fail(t, "the websocket was not closed after the first message")
+		}
+	})
+}
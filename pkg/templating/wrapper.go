@@ -0,0 +1,59 @@
+package templating
+
+import (
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+const (
+	wrapperAttribute = "wrapper"
+	// wrapperNone splices the content of a fragment without any wrapping element.
+	wrapperNone = "none"
+)
+
+// wrapperFor returns the tag wrapping the content of the fragment, where the wrapper attribute
+// of the fragment overrides the configured default.
+func (t *Templater) wrapperFor(element html.Node) string {
+	if value, ok := lookupAttribute(element, wrapperAttribute); ok {
+		return value
+	}
+	return t.wrapper
+}
+
+// insert replaces the fragment element, or the layout slot it names, by its content wrapped as configured.
+func (t *Templater) insert(element, fragment *html.Node) {
+	target := element
+	if slot := slotFor(element); slot != nil {
+		element.Parent.RemoveChild(element)
+		target = slot
+	}
+	parent := target.Parent
+
+	switch tag := t.wrapperFor(*element); tag {
+	case "":
+		parent.InsertBefore(fragment, target)
+	case wrapperNone:
+		for _, child := range detachChildren(fragment) {
+			parent.InsertBefore(child, target)
+		}
+	default:
+		fragment.Data = tag
+		fragment.DataAtom = atom.Lookup([]byte(tag))
+		parent.InsertBefore(fragment, target)
+	}
+
+	parent.RemoveChild(target)
+}
+
+// detachChildren unlinks the children of the node, so they can be inserted elsewhere.
+func detachChildren(node *html.Node) []*html.Node {
+	var children []*html.Node
+	for child := node.FirstChild; child != nil; {
+		next := child.NextSibling
+		child.Parent, child.PrevSibling, child.NextSibling = nil, nil, nil
+		children = append(children, child)
+		child = next
+	}
+	node.FirstChild, node.LastChild = nil, nil
+	return children
+}
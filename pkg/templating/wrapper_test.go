@@ -0,0 +1,50 @@
+package templating
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTemplater_Parse_Wrapper(t *testing.T) {
+	dummy := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("Content-Type", "text/html")
+		if request.URL.Path == "/broken" {
+			writer.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		writer.Write([]byte("<p>Foo</p><p>Bar</p>"))
+	}))
+	defer dummy.Close()
+
+	document := fmt.Sprintf(
+		`<html><body><fragment wrapper="none" src="%[1]s"></fragment><fragment wrapper="section" src="%[1]s"></fragment><fragment src="%[1]s"></fragment><fragment wrapper="none" src="%[1]s/broken"><i>Baz</i></fragment></body></html>`,
+		dummy.URL,
+	)
+
+	tt := []struct {
+		options  []Option
+		expected string
+	}{
+		{
+			expected: "<html><head></head><body><p>Foo</p><p>Bar</p><section><p>Foo</p><p>Bar</p></section><><p>Foo</p><p>Bar</p></><i>Baz</i></body></html>",
+		},
+		{
+			options:  []Option{WithWrapper("div")},
+			expected: "<html><head></head><body><p>Foo</p><p>Bar</p><section><p>Foo</p><p>Bar</p></section><div><p>Foo</p><p>Bar</p></div><i>Baz</i></body></html>",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run("", func(t *testing.T) {
+			templater := New(tc.options...)
+			actual, err := templater.Parse(strings.NewReader(document))
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+}